@@ -12,3 +12,12 @@ var iconRunning []byte
 
 //go:embed assets/icon_alert.png
 var iconAlert []byte
+
+//go:embed assets/icon_work.png
+var iconWork []byte
+
+//go:embed assets/icon_short_break.png
+var iconShortBreak []byte
+
+//go:embed assets/icon_long_break.png
+var iconLongBreak []byte
@@ -12,3 +12,12 @@ var iconRunning []byte
 
 //go:embed assets/icon_alert.ico
 var iconAlert []byte
+
+//go:embed assets/icon_work.ico
+var iconWork []byte
+
+//go:embed assets/icon_short_break.ico
+var iconShortBreak []byte
+
+//go:embed assets/icon_long_break.ico
+var iconLongBreak []byte
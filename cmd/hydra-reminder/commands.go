@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+	"hydra-reminder/internal/webhook"
+)
+
+// commands run instead of the tray app when their name is the first argument.
+var commands = map[string]func(args []string) error{
+	"profile": profileCommand,
+	"export":  exportCommand,
+	"import":  importCommand,
+	"ctl":     ctlCommand,
+	"watch":   watchCommand,
+	"webhook": webhookCommand,
+}
+
+// runCommand runs the subcommand named by args[0], if there is one, and
+// reports whether it did.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return false
+	}
+	if err := cmd(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "hydra-reminder %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// commandFlags is a flag set with the --config and --config-dir flags every
+// subcommand shares.
+type commandFlags struct {
+	*flag.FlagSet
+	configPath *string
+	configDir  *string
+}
+
+func newCommandFlags(name, usage string) *commandFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hydra-reminder %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return &commandFlags{
+		FlagSet:    fs,
+		configPath: fs.String("config", os.Getenv("HYDRA_CONFIG"), "path to config.json (env HYDRA_CONFIG)"),
+		configDir:  fs.String("config-dir", os.Getenv("HYDRA_CONFIG_DIR"), "directory for config and state files (env HYDRA_CONFIG_DIR)"),
+	}
+}
+
+// parse parses args and points the config package at --config and
+// --config-dir if given.
+func (fs *commandFlags) parse(args []string) {
+	fs.Parse(args)
+	if *fs.configDir != "" {
+		config.SetDir(*fs.configDir)
+	}
+	if *fs.configPath != "" {
+		config.SetPath(*fs.configPath)
+	}
+}
+
+// loadConfig loads the config for command. A config that loaded with
+// problems, the invalid fields reset to their defaults, is used after a
+// warning, as the app does at startup.
+func loadConfig(command string) (*config.Config, error) {
+	cfg, err := config.Load()
+	return usableConfig(command, cfg, err)
+}
+
+// peekConfig is loadConfig for commands that only read the config, it never
+// changes the file, see config.Peek.
+func peekConfig(command string) (*config.Config, error) {
+	cfg, err := config.Peek()
+	return usableConfig(command, cfg, err)
+}
+
+func usableConfig(command string, cfg *config.Config, err error) (*config.Config, error) {
+	if err != nil && cfg != nil {
+		fmt.Fprintf(os.Stderr, "hydra-reminder %s: warning: %v\n", command, err)
+		return cfg, nil
+	}
+	return cfg, err
+}
+
+// profileCommand lists profiles or switches the active one. A running instance
+// picks up the switch through its config file watcher.
+func profileCommand(args []string) error {
+	fs := newCommandFlags("profile", "profile [list | <name> | save <name>]")
+	fs.parse(args)
+
+	cfg, err := loadConfig("profile")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fs.NArg() == 0 || (fs.NArg() == 1 && fs.Arg(0) == "list"):
+		for _, name := range cfg.ProfileNames() {
+			marker := " "
+			if name == cfg.ActiveProfile {
+				marker = "*"
+			}
+			p := cfg.Profiles[name]
+			fmt.Printf("%s %s\t%d min, %s, snooze %d min\n", marker, name, p.DurationMinutes, p.AlertStyle, p.SnoozeMinutes)
+		}
+		return nil
+	case fs.NArg() == 2 && fs.Arg(0) == "save":
+		if err := cfg.SaveProfile(fs.Arg(1)); err != nil {
+			return err
+		}
+		fmt.Printf("Saved current settings as profile %q\n", fs.Arg(1))
+	case fs.NArg() == 1:
+		if err := cfg.SwitchProfile(fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to profile %q\n", fs.Arg(0))
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	return config.Save(cfg)
+}
+
+// exportCommand writes the settings bundle to a file, or stdout for "-".
+func exportCommand(args []string) error {
+	fs := newCommandFlags("export", "export [flags] <file | ->")
+	fs.parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := peekConfig("export")
+	if err != nil {
+		return err
+	}
+	data, err := config.Export(cfg)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if fs.Arg(0) == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(fs.Arg(0), data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Exported settings to %s\n", fs.Arg(0))
+	return nil
+}
+
+// importCommand applies a settings bundle, showing what changes. A running
+// instance picks up the new settings through its config file watcher.
+func importCommand(args []string) error {
+	fs := newCommandFlags("import", "import [flags] <file | ->")
+	dryRun := fs.Bool("dry-run", false, "only show what would change")
+	fs.parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	load := loadConfig
+	if *dryRun {
+		load = peekConfig
+	}
+	current, err := load("import")
+	if err != nil {
+		return err
+	}
+	imported, err := config.Import(data, current)
+	if err != nil {
+		return err
+	}
+
+	changes, err := config.Diff(current, imported)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if *dryRun {
+		fmt.Printf("Dry run, %d change(s) not applied.\n", len(changes))
+		return nil
+	}
+	if err := config.Save(imported); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d change(s).\n", len(changes))
+	return nil
+}
+
+// ctlCommand sends a command to the running instance over its control socket
+// and prints the timer status it replies with.
+func ctlCommand(args []string) error {
+	fs := newCommandFlags("ctl", "ctl [flags] <"+strings.Join(control.Commands, " | ")+"> [minutes]")
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	fs.parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 || !slices.Contains(control.Commands, fs.Arg(0)) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	req := control.Request{Command: fs.Arg(0)}
+	if fs.NArg() == 2 {
+		if req.Command != "snooze" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		minutes, err := strconv.Atoi(fs.Arg(1))
+		if err != nil || minutes <= 0 {
+			return fmt.Errorf("invalid minutes %q", fs.Arg(1))
+		}
+		req.Minutes = minutes
+	}
+
+	resp, err := control.Send(req)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(resp.Status)
+	}
+	st := resp.Status
+	line := st.State
+	if label := phaseLabel(st); label != "" {
+		line += ", " + label
+	}
+	if st.State == "running" || st.State == "paused" {
+		line += fmt.Sprintf(", %02d:%02d left", st.RemainingSeconds/60, st.RemainingSeconds%60)
+	}
+	if st.State == "break" {
+		line += fmt.Sprintf(", %02d:%02d so far", st.BreakSeconds/60, st.BreakSeconds%60)
+	}
+	if held := heldText(st); held != "" {
+		line += ", " + held
+	}
+	if st.Profile != "" {
+		line += fmt.Sprintf(" (profile %s)", st.Profile)
+	}
+	fmt.Println(line)
+	return nil
+}
+
+// webhookCommand sends a sample event through the configured webhooks.
+func webhookCommand(args []string) error {
+	fs := newCommandFlags("webhook", "webhook test [flags]")
+	event := fs.String("event", "alert", "event to send: "+strings.Join(config.WebhookEvents, ", "))
+	live := fs.Bool("send", false, "send to the real endpoints instead of a local stand-in server")
+	if len(args) == 0 || args[0] != "test" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fs.parse(args[1:])
+	if fs.NArg() != 0 || !slices.Contains(config.WebhookEvents, *event) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig("webhook")
+	if err != nil {
+		return err
+	}
+	if len(cfg.Webhooks) == 0 {
+		return fmt.Errorf("no webhooks in config.json")
+	}
+	return webhook.Test(cfg.Webhooks, *event, cfg.ActiveProfile, *live, os.Stdout)
+}
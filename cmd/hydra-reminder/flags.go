@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hydra-reminder/internal/config"
+)
+
+const usageHeader = `Usage: hydra-reminder [flags]
+       hydra-reminder ctl <status | start | stop | toggle | reset | snooze [minutes] | pause | resume | toggle-pause>
+       hydra-reminder watch [--plain] [--once]
+       hydra-reminder webhook test [--event alert|reset|snooze] [--send]
+       hydra-reminder profile [list | <name> | save <name>]
+       hydra-reminder export <file | ->
+       hydra-reminder import [--dry-run] <file | ->
+
+Every setting in config.json can be overridden for a single session, either
+with a flag named after the setting (alert_style -> --alert-style) or with an
+environment variable (alert_style -> HYDRA_ALERT_STYLE). Settings inside an
+object use a dot (pomodoro.enabled -> --pomodoro.enabled, HYDRA_POMODORO_ENABLED).
+Lists and maps (autostart_args, webhooks, profiles) are only read from
+config.json. Overrides are never written back to config.json.
+
+Precedence: flags > environment > config file > defaults
+
+Config and state files live in --config-dir if given, else in a HydraReminder
+folder next to the executable when a hydra-reminder.portable file is there
+(portable mode), else in the user's config directory.
+
+With --startup-delay the app waits that long before it starts, autostart
+entries pass autostart_delay_seconds this way. It waits only on the first start
+in a login session, not when systemd restarts it, and not when GNOME already
+waited for the entry's X-GNOME-Autostart-Delay.
+
+With --headless there is no tray icon. Alerts go to the log and the hooks in
+config.json, and the instance is controlled with hotkeys and "ctl".
+
+Flags:
+`
+
+// parseFlags parses the command line and HYDRA_* environment into overrides,
+// and points the config package at --config and --config-dir if given. It
+// also reports whether --headless was given and the --startup-delay.
+func parseFlags() (*config.Overrides, bool, time.Duration) {
+	overrides := config.NewOverrides()
+
+	configPath := flag.String("config", os.Getenv("HYDRA_CONFIG"), "path to config.json (env HYDRA_CONFIG)")
+	configDir := flag.String("config-dir", os.Getenv("HYDRA_CONFIG_DIR"), "directory for config and state files (env HYDRA_CONFIG_DIR)")
+	headless := flag.Bool("headless", envBool("HYDRA_HEADLESS"), "run without a tray icon (env HYDRA_HEADLESS)")
+	startupDelay := flag.Duration("startup-delay", 0, "wait this long before starting, e.g. 30s, for the tray host to come up after login")
+	overrides.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usageHeader)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *configDir != "" {
+		config.SetDir(*configDir)
+	}
+	if *configPath != "" {
+		config.SetPath(*configPath)
+	}
+	if err := overrides.LoadEnv(os.Environ()); err != nil {
+		slog.Warn("Ignoring environment overrides", "err", err)
+	}
+	return overrides, *headless, *startupDelay
+}
+
+// startupWait is how much of --startup-delay to wait. The delay gives the tray
+// host time to come up after login, so only the first start in the session
+// waits: it leaves a marker in $XDG_RUNTIME_DIR, which is emptied at logout,
+// and later starts such as systemd restarts after a crash find it. The wait is
+// also skipped when gnome-session launched the app from the XDG entry, it has
+// already waited for X-GNOME-Autostart-Delay and sets DESKTOP_AUTOSTART_ID.
+func startupWait(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		f, err := os.OpenFile(filepath.Join(dir, "hydra-reminder.started"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			return 0
+		} else if err != nil {
+			slog.Debug("No startup marker", "err", err)
+		} else {
+			f.Close()
+		}
+	}
+	if os.Getenv("DESKTOP_AUTOSTART_ID") != "" && strings.Contains(os.Getenv("XDG_CURRENT_DESKTOP"), "GNOME") {
+		return 0
+	}
+	return delay
+}
+
+// envBool reads a boolean environment variable, unset or invalid is false.
+func envBool(name string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(name))
+	return b
+}
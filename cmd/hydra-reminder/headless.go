@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"hydra-reminder/internal/adaptive"
+	"hydra-reminder/internal/breaks"
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/hooks"
+	"hydra-reminder/internal/hotkey"
+	"hydra-reminder/internal/httpapi"
+	"hydra-reminder/internal/logging"
+	"hydra-reminder/internal/metrics"
+	"hydra-reminder/internal/mqtt"
+	"hydra-reminder/internal/timer"
+	"hydra-reminder/internal/webhook"
+)
+
+// runHeadless runs the timer, hotkeys, hooks and control socket without a
+// tray, for window managers without one, SSH sessions and systemd services.
+// It returns on SIGINT or SIGTERM.
+func runHeadless(cfg *config.Config, overrides *config.Overrides) {
+	live := config.NewLive(cfg)
+	// Set once the socket is up, Notify does nothing until then.
+	var srv *control.Server
+
+	tm := timer.NewManager(
+		func() {
+			hooks.Run("start", live.Load().Hooks.OnStart)
+			srv.Notify()
+		},
+		func() {
+			slog.Info("Alert: time to stand up / drink water")
+			hooks.Run("alert", live.Load().Hooks.OnAlert)
+			srv.Notify()
+		},
+		func() {
+			hooks.Run("stop", live.Load().Hooks.OnStop)
+			srv.Notify()
+		},
+		func() {
+			hooks.Run("pause", live.Load().Hooks.OnPause)
+			srv.Notify()
+		},
+	)
+	tm.SetOnPhase(func() {
+		hooks.Run("alert", live.Load().Hooks.OnAlert)
+	})
+	tm.SetPomodoro(pomodoroSettings(cfg))
+	tm.SetBreakTracking(cfg.Breaks.Enabled)
+	tm.SetAlertLimit(alertLimit(cfg))
+	tm.Subscribe(func(ev timer.Event) {
+		if ev.Kind == timer.EventBreak || ev.Kind == timer.EventHold {
+			srv.Notify()
+		}
+	})
+	met := metrics.New(tm)
+	if wh, err := webhook.New(live); err != nil {
+		slog.Error("Webhooks unavailable", "err", err)
+	} else {
+		tm.Subscribe(wh.Observe)
+		stopWebhooks := make(chan struct{})
+		go wh.Run(stopWebhooks)
+		defer close(stopWebhooks)
+	}
+	h := &headless{cfg: cfg, live: live, timer: tm, overrides: overrides}
+	hist, err := history.Open()
+	if err != nil {
+		slog.Warn("History unavailable", "err", err)
+	}
+	restoreAlerts(tm, hist)
+	br := breaks.New(tm, live, hist)
+	tm.Subscribe(br.Observe)
+	stopBreaks := make(chan struct{})
+	go br.Run(stopBreaks)
+	defer close(stopBreaks)
+	tm.Subscribe(adaptive.New(live, hist, h.applyDuration).Observe)
+
+	hotkey.Init(func() {
+		tm.Reset()
+	})
+	hotkey.InitChord(chordHandler(tm, live, hist))
+	h.registerHotkeys(config.Config{})
+
+	srv, err = control.Listen(tm, live)
+	if errors.Is(err, control.ErrRunning) {
+		// Exit 0 like the tray app, so Restart=on-failure leaves it alone.
+		slog.Info("Not starting", "err", err)
+		os.Exit(0)
+	} else if err != nil {
+		slog.Error("Control socket unavailable", "err", err)
+		os.Exit(1)
+	}
+	defer srv.Close()
+	api := httpapi.New(srv, met)
+	api.Apply(cfg.HTTPEnabled, cfg.HTTPPort)
+	defer api.Apply(false, 0)
+	mq := mqtt.New(srv)
+	mq.Apply(cfg.MQTT)
+	defer mq.Apply(config.MQTT{})
+
+	if path, err := config.GetConfigPath(); err == nil {
+		stopWatch := config.Watch(path, func(newCfg *config.Config, err error) {
+			if err != nil {
+				slog.Warn("Ignoring config change", "err", err)
+				return
+			}
+			if err := overrides.Apply(newCfg); err != nil {
+				slog.Warn("Ignoring config change", "err", err)
+				return
+			}
+			logging.SetLevel(newCfg.LogLevel)
+			api.Apply(newCfg.HTTPEnabled, newCfg.HTTPPort)
+			mq.Apply(newCfg.MQTT)
+			h.reload(newCfg)
+		})
+		defer stopWatch()
+	}
+
+	tm.Start(live.Load().Duration())
+	slog.Info("Running headless, control socket ready")
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+
+	hotkey.Unregister()
+	hotkey.UnregisterChord()
+}
+
+// headless applies config changes the way the tray does, minus the menu.
+// Like the tray it changes a config of its own and publishes it to live.
+type headless struct {
+	live      *config.Live
+	timer     *timer.Manager
+	overrides *config.Overrides
+
+	// mu serializes changes from the config watcher and the adaptive interval.
+	mu  sync.Mutex
+	cfg *config.Config
+}
+
+// reload switches to cfg.
+func (h *headless) reload(cfg *config.Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := *h.cfg
+	*h.cfg = *cfg
+	h.live.Store(h.cfg)
+	h.registerHotkeys(old)
+	h.timer.SetPomodoro(pomodoroSettings(cfg))
+	h.timer.SetBreakTracking(cfg.Breaks.Enabled)
+	h.timer.SetAlertLimit(alertLimit(cfg))
+
+	if old.DurationMinutes != cfg.DurationMinutes {
+		switch h.timer.GetState() {
+		case timer.StateRunning, timer.StateAlerting:
+			h.timer.Start(cfg.Duration())
+		default:
+			h.timer.SetDuration(cfg.Duration())
+		}
+	}
+}
+
+// applyDuration switches to an interval of mins minutes and saves it, for the
+// adaptive interval's auto mode.
+func (h *headless) applyDuration(mins int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg.DurationMinutes = mins
+	h.live.Store(h.cfg)
+	if err := config.Save(h.overrides.ForSave(h.cfg)); err != nil {
+		slog.Error("Failed to save config", "err", err)
+	}
+	switch h.timer.GetState() {
+	case timer.StateRunning, timer.StateAlerting:
+		h.timer.Start(h.cfg.Duration())
+	default:
+		h.timer.SetDuration(h.cfg.Duration())
+	}
+}
+
+// registerHotkeys registers or unregisters the hotkeys that differ from old.
+// Without an X display they fail, which only matters if they are enabled.
+func (h *headless) registerHotkeys(old config.Config) {
+	cfg := h.cfg
+	if old.HotkeyEnabled != cfg.HotkeyEnabled || old.HotkeyModifiers != cfg.HotkeyModifiers ||
+		old.HotkeyResetKey != cfg.HotkeyResetKey {
+		if cfg.HotkeyEnabled {
+			if err := hotkey.Register(cfg.HotkeyModifiers, cfg.HotkeyResetKey); err != nil {
+				slog.Error("Failed to register hotkeys", "err", err)
+			}
+		} else {
+			hotkey.Unregister()
+		}
+	}
+
+	if old.ChordEnabled != cfg.ChordEnabled || old.HotkeyModifiers != cfg.HotkeyModifiers ||
+		old.ChordLeaderKey != cfg.ChordLeaderKey || old.ChordTimeoutMs != cfg.ChordTimeoutMs {
+		if cfg.ChordEnabled {
+			timeout := time.Duration(cfg.ChordTimeoutMs) * time.Millisecond
+			if err := hotkey.RegisterChord(cfg.HotkeyModifiers, cfg.ChordLeaderKey, timeout); err != nil {
+				slog.Error("Failed to register chord leader", "err", err)
+			}
+		} else {
+			hotkey.UnregisterChord()
+		}
+	}
+}
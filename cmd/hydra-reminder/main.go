@@ -1,41 +1,142 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"os"
+	"time"
 
+	"hydra-reminder/internal/adaptive"
+	"hydra-reminder/internal/autostart"
+	"hydra-reminder/internal/breaks"
 	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/hooks"
 	"hydra-reminder/internal/hotkey"
+	"hydra-reminder/internal/httpapi"
+	"hydra-reminder/internal/logging"
+	"hydra-reminder/internal/metrics"
+	"hydra-reminder/internal/mqtt"
 	"hydra-reminder/internal/timer"
 	"hydra-reminder/internal/tray"
+	"hydra-reminder/internal/webhook"
 
 	_ "embed"
 )
 
-
-
 func main() {
-	log.SetOutput(os.Stderr)
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	overrides, headless, startupDelay := parseFlags()
+	if err := logging.Start(); err != nil {
+		slog.Warn("Logging to stderr only", "err", err)
+	}
+	if wait := startupWait(startupDelay); wait > 0 {
+		slog.Info("Waiting before starting", "delay", wait)
+		time.Sleep(wait)
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
-		log.Printf("Failed to load config: %v", err)
-		cfg = config.DefaultConfig()
+		slog.Warn("Failed to load config", "err", err)
+		if cfg == nil {
+			cfg = config.DefaultConfig()
+		}
 	}
 
-	app := tray.NewApp(cfg)
+	if err := overrides.Apply(cfg); err != nil {
+		slog.Error("Invalid settings", "err", err)
+		os.Exit(2)
+	}
+	logging.SetLevel(cfg.LogLevel)
+
+	if err := autostart.SetBackend(cfg.AutostartBackend); err != nil {
+		slog.Warn("Using the default autostart backend", "err", err)
+	}
+	autostart.SetOptions(autostartOptions(cfg))
+
+	if headless {
+		runHeadless(cfg, overrides)
+		return
+	}
+
+	live := config.NewLive(cfg)
+	app := tray.NewApp(live)
+	app.SetOverrides(overrides)
+
+	var loadErr *config.LoadError
+	if errors.As(err, &loadErr) {
+		app.SetConfigProblems(loadErr.Problems())
+	} else if err != nil {
+		app.SetConfigProblems([]string{err.Error()})
+	}
+
+	// Set once the socket is up, Notify does nothing until then.
+	var srv *control.Server
 
 	tm := timer.NewManager(
 		func() {
 			app.OnRunning()
+			hooks.Run("start", live.Load().Hooks.OnStart)
+			srv.Notify()
 		},
 		func() {
 			app.OnAlert()
+			hooks.Run("alert", live.Load().Hooks.OnAlert)
+			srv.Notify()
 		},
 		func() {
 			app.OnStop()
+			hooks.Run("stop", live.Load().Hooks.OnStop)
+			srv.Notify()
+		},
+		func() {
+			app.OnPause()
+			hooks.Run("pause", live.Load().Hooks.OnPause)
+			srv.Notify()
 		},
 	)
+	// Before the other observers, the callbacks show the phase app records.
+	tm.Subscribe(app.Observe)
+	tm.SetOnPhase(func() {
+		app.OnPhase()
+		hooks.Run("alert", live.Load().Hooks.OnAlert)
+	})
+	tm.SetPomodoro(pomodoroSettings(cfg))
+	tm.SetBreakTracking(cfg.Breaks.Enabled)
+	tm.SetAlertLimit(alertLimit(cfg))
+	tm.Subscribe(func(ev timer.Event) {
+		switch ev.Kind {
+		case timer.EventBreak:
+			app.OnBreak()
+			srv.Notify()
+		case timer.EventHold:
+			// Shows the held alert in the tooltip, without the start hook.
+			// app.Observe has recorded the event's held counts already.
+			app.OnRunning()
+			srv.Notify()
+		}
+	})
+	met := metrics.New(tm)
+	if wh, err := webhook.New(live); err != nil {
+		slog.Error("Webhooks unavailable", "err", err)
+	} else {
+		tm.Subscribe(wh.Observe)
+		go wh.Run(nil)
+	}
+	hist, err := history.Open()
+	if err != nil {
+		slog.Warn("History unavailable", "err", err)
+	}
+	restoreAlerts(tm, hist)
+	br := breaks.New(tm, live, hist)
+	tm.Subscribe(br.Observe)
+	go br.Run(nil)
+	ad := adaptive.New(live, hist, app.ApplyDuration)
+	tm.Subscribe(ad.Observe)
 
 	// Since app needs the timer manager, we can set it. We'll modify tray slightly or access the field if exported,
 	// but it isn't exported. We can just add a SetTimerManager method, or we bypass that by defining a cyclic init.
@@ -48,6 +149,143 @@ func main() {
 		tm.Reset()
 	})
 
+	hotkey.InitChord(chordHandler(tm, live, hist))
+
 	app.SetTimerManager(tm)
+	app.SetPhaseIcons(iconWork, iconShortBreak, iconLongBreak)
+	app.SetBreakTracker(br)
+	app.SetAdvisor(ad)
+	app.SetHistory(hist)
+
+	var api *httpapi.Service
+	var mq *mqtt.Service
+	srv, err = control.Listen(tm, live)
+	if errors.Is(err, control.ErrRunning) {
+		// Not a failure: exiting 0 keeps systemd's Restart=on-failure from
+		// starting this copy again every few seconds.
+		slog.Info("Not starting", "err", err)
+		os.Exit(0)
+	} else if err != nil {
+		slog.Warn("Control socket unavailable", "err", err)
+		if cfg.HTTPEnabled {
+			slog.Warn("HTTP API disabled, it needs the control socket")
+		}
+		if cfg.MQTT.Broker != "" {
+			slog.Warn("MQTT disabled, it needs the control socket")
+		}
+	} else {
+		api = httpapi.New(srv, met)
+		api.Apply(cfg.HTTPEnabled, cfg.HTTPPort)
+		mq = mqtt.New(srv)
+		mq.Apply(cfg.MQTT)
+	}
+
+	if path, err := config.GetConfigPath(); err == nil {
+		stopWatch := config.Watch(path, func(newCfg *config.Config, err error) {
+			if err != nil {
+				slog.Warn("Ignoring config change", "err", err)
+				return
+			}
+			if err := overrides.Apply(newCfg); err != nil {
+				slog.Warn("Ignoring config change", "err", err)
+				return
+			}
+			logging.SetLevel(newCfg.LogLevel)
+			autostart.SetOptions(autostartOptions(newCfg))
+			if api == nil && newCfg.HTTPEnabled {
+				slog.Warn("HTTP API disabled, it needs the control socket")
+			}
+			api.Apply(newCfg.HTTPEnabled, newCfg.HTTPPort)
+			if mq == nil && newCfg.MQTT.Broker != "" {
+				slog.Warn("MQTT disabled, it needs the control socket")
+			}
+			mq.Apply(newCfg.MQTT)
+			tm.SetPomodoro(pomodoroSettings(newCfg))
+			tm.SetBreakTracking(newCfg.Breaks.Enabled)
+			tm.SetAlertLimit(alertLimit(newCfg))
+			app.ReloadConfig(newCfg)
+		})
+		app.AtExit(stopWatch)
+	}
+
 	app.Run(iconStopped, iconRunning, iconAlert)
 }
+
+// chordHandler runs chord actions on tm with the settings currently in live,
+// drinks are recorded in hist.
+func chordHandler(tm *timer.Manager, live *config.Live, hist *history.Log) func(hotkey.Action) {
+	return func(a hotkey.Action) {
+		cfg := live.Load()
+		switch a {
+		case hotkey.ActionReset:
+			tm.Reset()
+		case hotkey.ActionSnooze:
+			tm.Snooze(time.Duration(cfg.SnoozeMinutes) * time.Minute)
+		case hotkey.ActionDrink:
+			logDrink(hist, cfg.ActiveProfile)
+			tm.Reset()
+		case hotkey.ActionPause:
+			tm.TogglePause()
+		}
+	}
+}
+
+// autostartOptions describes the login entry for cfg, with the running icon
+// as the app's icon.
+func autostartOptions(cfg *config.Config) autostart.Options {
+	return autostart.Options{
+		Args:  cfg.AutostartArgs,
+		Delay: time.Duration(cfg.AutostartDelaySeconds) * time.Second,
+		Icon:  iconRunning,
+	}
+}
+
+// pomodoroSettings returns the timer's Pomodoro settings for cfg, nil when
+// Pomodoro mode is off.
+func pomodoroSettings(cfg *config.Config) *timer.Pomodoro {
+	if !cfg.Pomodoro.Enabled {
+		return nil
+	}
+	work, shortBreak, longBreak := cfg.Pomodoro.Durations()
+	return &timer.Pomodoro{
+		Work:       work,
+		ShortBreak: shortBreak,
+		LongBreak:  longBreak,
+		Cycles:     cfg.Pomodoro.CyclesBeforeLongBreak,
+		Auto:       cfg.Pomodoro.AutoAdvance,
+	}
+}
+
+// logDrink records a drink in hist, which may be nil.
+func logDrink(hist *history.Log, profile string) {
+	slog.Info("Drink logged")
+	e := history.Entry{At: time.Now().UTC().Truncate(time.Second), Kind: history.KindDrink, Profile: profile}
+	if err := hist.Append(e); err != nil {
+		slog.Error("Failed to record drink", "err", err)
+	}
+}
+
+// alertLimit returns the timer's alert limit for cfg.
+func alertLimit(cfg *config.Config) timer.AlertLimit {
+	return timer.AlertLimit{
+		PerDay: cfg.AlertLimit.MaxPerDay,
+		MinGap: time.Duration(cfg.AlertLimit.MinGapMinutes) * time.Minute,
+	}
+}
+
+// restoreAlerts counts today's alerts in hist, which may be nil, towards the
+// daily cap, so a restart does not start counting from zero. The history has
+// the alerts that were acknowledged or stopped outside Pomodoro mode.
+func restoreAlerts(tm *timer.Manager, hist *history.Log) {
+	if hist == nil {
+		return
+	}
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	alerts := hist.Since(midnight, history.KindAck, history.KindSkip)
+	var last time.Time
+	if n := len(alerts); n > 0 {
+		last = alerts[n-1].At.Add(-time.Duration(alerts[n-1].Delay) * time.Second)
+	}
+	tm.RestoreAlerts(len(alerts), last)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"hydra-reminder/internal/control"
+)
+
+// watchCommand prints the running instance's status for status bars, one line
+// per state change and per second. It keeps retrying while no instance runs,
+// bars usually start before the app does.
+func watchCommand(args []string) error {
+	fs := newCommandFlags("watch", "watch [flags]")
+	plain := fs.Bool("plain", false, "print plain text for polybar and i3blocks instead of waybar JSON")
+	once := fs.Bool("once", false, "print the current status once and exit")
+	fs.parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	format := waybarLine
+	if *plain {
+		format = plainLine
+	}
+
+	if *once {
+		resp, err := control.Send(control.Request{Command: "status"})
+		if err != nil {
+			fmt.Println(format(nil))
+			return nil
+		}
+		fmt.Println(format(resp.Status))
+		return nil
+	}
+
+	var last string
+	emit := func(st *control.Status) {
+		line := format(st)
+		if line != last {
+			fmt.Println(line)
+			last = line
+		}
+	}
+	for {
+		control.Watch(func(st control.Status) { emit(&st) })
+		emit(nil)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// waybarOutput is the JSON a waybar custom module with "return-type": "json" reads.
+type waybarOutput struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+	Alt        string `json:"alt,omitempty"` // Pomodoro phase, for format-icons
+}
+
+// waybarLine formats st for waybar. A nil st means no instance is running.
+func waybarLine(st *control.Status) string {
+	out := waybarOutput{Text: plainLine(st), Class: "offline", Tooltip: "HydraReminder is not running"}
+	if st != nil {
+		out.Class = st.State
+		out.Percentage = remainingPercent(st)
+		out.Alt = st.Phase
+		out.Tooltip = fmt.Sprintf("HydraReminder - %s", st.State)
+		if label := phaseLabel(st); label != "" {
+			out.Tooltip += fmt.Sprintf("\n%s", label)
+		}
+		if st.State == "running" || st.State == "paused" {
+			out.Tooltip += fmt.Sprintf("\n%s left of %s", clock(st.RemainingSeconds), clock(st.DurationSeconds))
+		}
+		if st.State == "break" {
+			out.Tooltip += fmt.Sprintf("\nOn a break for %s", clock(st.BreakSeconds))
+		}
+		if held := heldText(st); held != "" {
+			out.Tooltip += "\n" + strings.ToUpper(held[:1]) + held[1:]
+		}
+		if st.Profile != "" {
+			out.Tooltip += fmt.Sprintf("\nProfile: %s", st.Profile)
+		}
+	}
+	data, _ := json.Marshal(out)
+	return string(data)
+}
+
+// plainLine formats st as short text. A nil st means no instance is running.
+func plainLine(st *control.Status) string {
+	if st == nil {
+		return "offline"
+	}
+	if st.Capped && st.State == "running" {
+		return statusLine(st) + " (limit reached)"
+	}
+	return statusLine(st)
+}
+
+// statusLine is plainLine without the note on the daily limit.
+func statusLine(st *control.Status) string {
+	if label := phaseLabel(st); label != "" && st.State != "stopped" {
+		switch st.State {
+		case "running":
+			return label + " " + clock(st.RemainingSeconds)
+		case "paused":
+			return label + " " + clock(st.RemainingSeconds) + " paused"
+		}
+		return label + " done"
+	}
+	switch st.State {
+	case "running":
+		return clock(st.RemainingSeconds)
+	case "paused":
+		return clock(st.RemainingSeconds) + " paused"
+	case "alerting":
+		return "Stand up / drink water!"
+	case "break":
+		return "break " + clock(st.BreakSeconds)
+	}
+	return st.State
+}
+
+// heldText describes the alerts the alert limit held back, e.g. "2 alerts
+// merged into the last one, daily limit reached, 3 held back", or returns "".
+func heldText(st *control.Status) string {
+	var parts []string
+	switch {
+	case st.Merged == 1:
+		parts = append(parts, "1 alert merged into the last one")
+	case st.Merged > 1:
+		parts = append(parts, fmt.Sprintf("%d alerts merged into the last one", st.Merged))
+	}
+	if st.Capped {
+		parts = append(parts, fmt.Sprintf("daily limit reached, %d held back", st.HeldToday))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// phaseLabel names the Pomodoro phase, e.g. "Work 3/4", "" outside Pomodoro mode.
+func phaseLabel(st *control.Status) string {
+	switch st.Phase {
+	case "work":
+		return fmt.Sprintf("Work %d/%d", st.Cycle, st.Cycles)
+	case "short_break":
+		return "Short break"
+	case "long_break":
+		return "Long break"
+	}
+	return ""
+}
+
+// remainingPercent is the share of the interval that is left, 0 to 100.
+func remainingPercent(st *control.Status) int {
+	if st.DurationSeconds <= 0 || (st.State != "running" && st.State != "paused") {
+		return 0
+	}
+	return min(100, st.RemainingSeconds*100/st.DurationSeconds)
+}
+
+func clock(seconds int) string {
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,241 @@
+// Package adaptive suggests a reminder interval from how recent alerts were
+// answered. Each alert's effective interval is the time from the start of
+// its countdown to its acknowledgement, snoozes and the delay in answering
+// included, so always snoozing twice suggests an interval two snoozes
+// longer. Alerts ended with stop count twice as long, as they were not wanted
+// then either. The suggestion is the median of the recent ones, rounded to 5
+// minutes and kept within the configured bounds.
+package adaptive
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/timer"
+)
+
+const (
+	// window is how many recent alerts of the active profile count, of
+	// which at least minAlerts are needed for a suggestion.
+	window    = 10
+	minAlerts = 5
+	// maxAge is how old an alert may be to count.
+	maxAge = 14 * 24 * time.Hour
+	// step is what suggestions are rounded to, in minutes.
+	step = 5
+)
+
+// Suggestion is the interval the recent alerts point to.
+type Suggestion struct {
+	// Minutes is the suggested interval, 0 without enough history.
+	Minutes int
+	// Change is true if Minutes differs from the current interval.
+	Change bool
+	// Summary says what is suggested, Reason what it is based on.
+	Summary string
+	Reason  string
+}
+
+// Advisor records how alerts are answered and makes suggestions from it.
+type Advisor struct {
+	cfg   *config.Live
+	log   *history.Log // nil without a history
+	apply func(minutes int)
+
+	mu         sync.Mutex
+	countStart time.Time // start of the current alert's countdown, zero if none
+	pausedAt   time.Time
+	alertAt    time.Time
+	snoozes    int
+	suggested  int // last suggestion logged, to log each only once
+}
+
+// New returns an advisor that records alerts in log, which may be nil. In
+// auto mode it calls apply with the new interval in minutes when the
+// suggestion changes. Subscribe Observe to the timer to use it.
+func New(cfg *config.Live, log *history.Log, apply func(minutes int)) *Advisor {
+	return &Advisor{cfg: cfg, log: log, apply: apply}
+}
+
+// Observe follows the timer from countdown start to acknowledgement. It is
+// meant for timer.Manager.Subscribe and does not block.
+func (a *Advisor) Observe(ev timer.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ev.Phase != timer.PhaseNone {
+		// Pomodoro phases have fixed lengths.
+		a.countStart = time.Time{}
+		return
+	}
+
+	switch ev.Kind {
+	case timer.EventAlert:
+		a.alertAt = ev.At
+	case timer.EventSnooze:
+		if ev.From == timer.StateAlerting {
+			a.snoozes++
+		} else if a.countStart.IsZero() {
+			a.begin(ev.At)
+		}
+	case timer.EventPause:
+		a.pausedAt = ev.At
+	case timer.EventResume:
+		if !a.countStart.IsZero() && !a.pausedAt.IsZero() {
+			// Paused time is not part of the interval.
+			a.countStart = a.countStart.Add(ev.At.Sub(a.pausedAt))
+		}
+		a.pausedAt = time.Time{}
+	case timer.EventReset, timer.EventBreak:
+		if ev.From == timer.StateAlerting {
+			a.record(history.KindAck, ev.At)
+		}
+		a.countStart = time.Time{}
+		if ev.Kind == timer.EventReset {
+			a.begin(ev.At)
+		}
+	case timer.EventStop:
+		if ev.From == timer.StateAlerting {
+			a.record(history.KindSkip, ev.At)
+		}
+		a.countStart = time.Time{}
+	case timer.EventStart, timer.EventReturn:
+		a.begin(ev.At)
+	}
+}
+
+// Internal func, assumes lock is held
+func (a *Advisor) begin(at time.Time) {
+	a.countStart, a.pausedAt, a.alertAt, a.snoozes = at, time.Time{}, time.Time{}, 0
+}
+
+// record adds the alert that ended at to the history and, in auto mode,
+// applies the suggestion that follows.
+// Internal func, assumes lock is held
+func (a *Advisor) record(kind string, at time.Time) {
+	if a.log == nil || a.countStart.IsZero() || a.alertAt.IsZero() {
+		return
+	}
+	e := history.Entry{
+		At:      at.UTC().Truncate(time.Second),
+		Kind:    kind,
+		Seconds: int(at.Sub(a.countStart).Round(time.Second).Seconds()),
+		Delay:   int(at.Sub(a.alertAt).Round(time.Second).Seconds()),
+		Snoozes: a.snoozes,
+		Profile: a.cfg.Load().ActiveProfile,
+	}
+	go func() {
+		if err := a.log.Append(e); err != nil {
+			slog.Error("Failed to record alert", "err", err)
+		}
+		a.update()
+	}()
+}
+
+// update logs a new suggestion and applies it in auto mode.
+func (a *Advisor) update() {
+	settings := a.cfg.Load().Adaptive
+	if !settings.Enabled {
+		return
+	}
+	s := a.Suggestion()
+	a.mu.Lock()
+	changed := s.Change && s.Minutes != a.suggested
+	if s.Change {
+		a.suggested = s.Minutes
+	}
+	a.mu.Unlock()
+	if !s.Change {
+		return
+	}
+
+	if settings.Auto {
+		slog.Info("Adaptive interval applied", "minutes", s.Minutes, "reason", s.Reason)
+		a.apply(s.Minutes)
+	} else if changed {
+		slog.Info("Adaptive interval suggested", "minutes", s.Minutes, "reason", s.Reason)
+	}
+}
+
+// Suggestion returns the current suggestion. It is empty if adaptive mode is
+// off.
+func (a *Advisor) Suggestion() Suggestion {
+	if a == nil {
+		return Suggestion{}
+	}
+	cfg := a.cfg.Load()
+	if !cfg.Adaptive.Enabled {
+		return Suggestion{}
+	}
+	var alerts []history.Entry
+	if a.log != nil {
+		for _, e := range a.log.Recent(10*window, history.KindAck, history.KindSkip) {
+			if e.Profile == cfg.ActiveProfile {
+				alerts = append(alerts, e)
+			}
+		}
+	}
+	return suggest(alerts, time.Now(), cfg.DurationMinutes, cfg.Adaptive)
+}
+
+// suggest makes the suggestion for an interval of current minutes from the
+// alerts, oldest first.
+func suggest(alerts []history.Entry, now time.Time, current int, bounds config.Adaptive) Suggestion {
+	alerts = slices.DeleteFunc(slices.Clone(alerts), func(e history.Entry) bool {
+		return now.Sub(e.At) > maxAge
+	})
+	if len(alerts) > window {
+		alerts = alerts[len(alerts)-window:]
+	}
+	if len(alerts) < minAlerts {
+		return Suggestion{
+			Summary: "Not enough alerts yet",
+			Reason:  fmt.Sprintf("%d of the %d needed in the last %d days", len(alerts), minAlerts, int(maxAge.Hours()/24)),
+		}
+	}
+
+	var intervals, delays []float64
+	snoozes, skipped := 0, 0
+	for _, e := range alerts {
+		minutes := float64(e.Seconds) / 60
+		if e.Kind == history.KindSkip {
+			minutes *= 2
+			skipped++
+		}
+		intervals = append(intervals, minutes)
+		delays = append(delays, float64(e.Delay))
+		snoozes += e.Snoozes
+	}
+	minutes := int(math.Round(median(intervals)/step)) * step
+	minutes = min(max(minutes, bounds.MinMinutes), bounds.MaxMinutes)
+
+	delay := time.Duration(median(delays)) * time.Second
+	s := Suggestion{
+		Minutes: minutes,
+		Change:  minutes != current,
+		Reason: fmt.Sprintf("Last %d alerts: %.1f snoozes each, %s to acknowledge, %d skipped",
+			len(alerts), float64(snoozes)/float64(len(alerts)), delay.Round(time.Second), skipped),
+	}
+	if s.Change {
+		s.Summary = fmt.Sprintf("Suggested: %d min instead of %d", minutes, current)
+	} else {
+		s.Summary = fmt.Sprintf("Suggested: keep %d min", current)
+	}
+	return s
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,106 @@
+package adaptive
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/timer"
+)
+
+func alerts(now time.Time, kind string, minutes, snoozes, count int) []history.Entry {
+	var out []history.Entry
+	for i := range count {
+		out = append(out, history.Entry{
+			At:      now.Add(-time.Duration(count-i) * time.Hour),
+			Kind:    kind,
+			Seconds: minutes * 60,
+			Delay:   30,
+			Snoozes: snoozes,
+		})
+	}
+	return out
+}
+
+func TestSuggest(t *testing.T) {
+	now := time.Now()
+	bounds := config.DefaultConfig().Adaptive
+	tests := []struct {
+		name    string
+		alerts  []history.Entry
+		current int
+		want    int
+		change  bool
+	}{
+		{"not enough", alerts(now, history.KindAck, 50, 2, minAlerts-1), 30, 0, false},
+		// 30 minutes and two 10 minute snoozes every time.
+		{"always snoozing twice", alerts(now, history.KindAck, 50, 2, 8), 30, 50, true},
+		{"answered on time", alerts(now, history.KindAck, 31, 0, 8), 30, 30, false},
+		{"skipped", alerts(now, history.KindSkip, 30, 0, 6), 30, 60, true},
+		{"clamped", alerts(now, history.KindAck, 300, 10, 6), 30, bounds.MaxMinutes, true},
+		{"too old", alerts(now.Add(-30*24*time.Hour), history.KindAck, 50, 2, 8), 30, 0, false},
+		{
+			"only the latest count",
+			append(alerts(now.Add(-time.Hour*24), history.KindAck, 90, 4, 10), alerts(now, history.KindAck, 45, 1, window)...),
+			30, 45, true,
+		},
+	}
+	for _, tt := range tests {
+		s := suggest(tt.alerts, now, tt.current, bounds)
+		if s.Minutes != tt.want || s.Change != tt.change {
+			t.Errorf("%s: suggested %d, change %v, want %d, %v (%s)", tt.name, s.Minutes, s.Change, tt.want, tt.change, s.Reason)
+		}
+	}
+
+	s := suggest(alerts(now, history.KindAck, 50, 2, 8), now, 30, bounds)
+	if !strings.Contains(s.Reason, "2.0 snoozes each") || !strings.Contains(s.Summary, "50 min instead of 30") {
+		t.Errorf("suggestion = %+v", s)
+	}
+}
+
+func TestObserveRecordsAlerts(t *testing.T) {
+	config.SetDir(t.TempDir())
+	t.Cleanup(func() { config.SetDir("") })
+	log, err := history.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	a := New(config.NewLive(cfg), log, func(int) {})
+
+	start := time.Now()
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	for _, ev := range []timer.Event{
+		{Kind: timer.EventStart, From: timer.StateStopped, At: at(0)},
+		{Kind: timer.EventPause, From: timer.StateRunning, At: at(10)},
+		{Kind: timer.EventResume, From: timer.StatePaused, At: at(15)},
+		{Kind: timer.EventAlert, From: timer.StateRunning, At: at(35)},
+		{Kind: timer.EventSnooze, From: timer.StateAlerting, At: at(36)},
+		{Kind: timer.EventAlert, From: timer.StateRunning, At: at(46)},
+		{Kind: timer.EventReset, From: timer.StateAlerting, At: at(48)},
+		{Kind: timer.EventAlert, From: timer.StateRunning, At: at(78)},
+		{Kind: timer.EventStop, From: timer.StateAlerting, At: at(80)},
+	} {
+		a.Observe(ev)
+	}
+
+	// Appends happen in the background.
+	var got []history.Entry
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got = log.Recent(window, history.KindAck, history.KindSkip); len(got) == 2 {
+			break
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("recorded %+v, want an acknowledged and a skipped alert", got)
+	}
+	ack, skip := got[0], got[1]
+	if ack.Kind != history.KindAck || ack.Seconds != 43*60 || ack.Delay != 2*60 || ack.Snoozes != 1 {
+		t.Errorf("acknowledged alert = %+v, want 43 minutes without the pause, 2 minutes delay, 1 snooze", ack)
+	}
+	if skip.Kind != history.KindSkip || skip.Seconds != 32*60 || skip.Snoozes != 0 {
+		t.Errorf("skipped alert = %+v, want 32 minutes, no snoozes", skip)
+	}
+}
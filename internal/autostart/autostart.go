@@ -0,0 +1,121 @@
+package autostart
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Status describes the autostart entry as found on disk.
+type Status int
+
+const (
+	StatusDisabled Status = iota
+	StatusEnabled
+	// StatusBroken is an entry that starts a different executable than this
+	// one, typically left behind after the app was moved or upgraded.
+	StatusBroken
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusDisabled:
+		return "disabled"
+	case StatusEnabled:
+		return "enabled"
+	case StatusBroken:
+		return "broken"
+	}
+	return "unknown"
+}
+
+// Options customise the installed entry.
+type Options struct {
+	// Args are passed to the executable, e.g. --profile work.
+	Args []string
+	// Delay postpones the start after login, giving the tray host time to come
+	// up. The entry passes it to the app as --startup-delay, so it works the
+	// same with every backend and desktop. The XDG entry also carries it as
+	// X-GNOME-Autostart-Delay, the app does not wait again after GNOME did.
+	Delay time.Duration
+	// Icon is a PNG installed into the user's icon theme for the XDG entry.
+	Icon []byte
+}
+
+var options Options
+
+// argv is the command line the entry runs: exePath, the delay if any, then Args.
+func (o Options) argv(exePath string) []string {
+	argv := []string{exePath}
+	if o.Delay > 0 {
+		argv = append(argv, "--startup-delay="+o.Delay.String())
+	}
+	return append(argv, o.Args...)
+}
+
+// SetOptions changes what the next Enable writes. It does not touch an entry
+// that is already installed.
+func SetOptions(o Options) {
+	options = o
+}
+
+// Check reports whether autostart is on and whether the entry starts this
+// executable. It also returns the executable the entry points at, if any.
+func Check() (Status, string, error) {
+	stored, found, err := storedPath()
+	if err != nil || !found {
+		return StatusDisabled, "", err
+	}
+	exePath, err := executablePath()
+	if err != nil {
+		return StatusDisabled, stored, err
+	}
+	if !samePath(stored, exePath) {
+		return StatusBroken, stored, nil
+	}
+	return StatusEnabled, stored, nil
+}
+
+// Heal rewrites a broken entry so it starts this executable and returns the
+// resulting status. Meant to be called once at startup.
+func Heal() (Status, error) {
+	status, stored, err := Check()
+	if err != nil || status != StatusBroken {
+		return status, err
+	}
+	if err := rewrite(); err != nil {
+		return StatusBroken, err
+	}
+	status, _, err = Check()
+	if err == nil && status == StatusEnabled {
+		slog.Info("Autostart entry updated to this executable", "was", stored)
+	}
+	return status, err
+}
+
+func executablePath() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(exePath)
+}
+
+// samePath compares two executable paths, resolving symlinks where possible.
+func samePath(a, b string) bool {
+	resolve := func(p string) string {
+		p = filepath.Clean(p)
+		if r, err := filepath.EvalSymlinks(p); err == nil {
+			return r
+		}
+		return p
+	}
+	a, b = resolve(a), resolve(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
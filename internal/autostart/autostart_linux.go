@@ -3,13 +3,43 @@
 package autostart
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"image"
+	_ "image/png"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const appName = "hydra-reminder"
 
+// Linux autostart backends.
+const (
+	// BackendXDG writes an XDG autostart .desktop file, started by the desktop session.
+	BackendXDG = "xdg"
+	// BackendSystemd installs a systemd --user service that is restarted on crashes.
+	BackendSystemd = "systemd"
+)
+
+var backend = BackendXDG
+
+// SetBackend selects what Enable installs. An empty name selects the default, BackendXDG.
+func SetBackend(name string) error {
+	switch name {
+	case "":
+		backend = BackendXDG
+	case BackendXDG, BackendSystemd:
+		backend = name
+	default:
+		return fmt.Errorf("unsupported autostart backend %q on linux", name)
+	}
+	return nil
+}
+
 func getAutostartPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -18,13 +48,23 @@ func getAutostartPath() (string, error) {
 	return filepath.Join(homeDir, ".config", "autostart", appName+".desktop"), nil
 }
 
+// Enable installs the selected backend and removes the other one, so the app
+// is never started twice.
 func Enable() error {
-	exePath, err := os.Executable()
-	if err != nil {
+	if backend == BackendSystemd {
+		if err := enableSystemd(); err != nil {
+			return err
+		}
+		return disableXDG()
+	}
+	if err := enableXDG(); err != nil {
 		return err
 	}
+	return disableSystemd()
+}
 
-	exePath, err = filepath.Abs(exePath)
+func enableXDG() error {
+	exePath, err := executablePath()
 	if err != nil {
 		return err
 	}
@@ -40,20 +80,131 @@ func Enable() error {
 		return err
 	}
 
-	desktopFileContent := fmt.Sprintf(`[Desktop Entry]
-Type=Application
-Name=Hydra Reminder
-Comment=Hydration and Activity Reminder
-Exec=%s
-Icon=utilities-terminal
-Terminal=false
-Categories=Utility;
-`, exePath)
+	icon := "utilities-terminal"
+	if len(options.Icon) > 0 {
+		if err := installIcon(options.Icon); err != nil {
+			slog.Warn("Failed to install icon, using a stock one", "err", err)
+		} else {
+			icon = appName
+		}
+	}
+
+	if err := os.WriteFile(autostartPath, []byte(desktopEntry(exePath, icon, options)), 0644); err != nil {
+		return err
+	}
+	slog.Debug("Autostart entry written", "path", autostartPath, "exec", exePath, "args", options.Args)
+	return nil
+}
+
+func desktopEntry(exePath, icon string, opts Options) string {
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	b.WriteString("Name=Hydra Reminder\n")
+	b.WriteString("Comment=Hydration and Activity Reminder\n")
+	fmt.Fprintf(&b, "Exec=%s\n", desktopExec(opts.argv(exePath)))
+	fmt.Fprintf(&b, "Icon=%s\n", icon)
+	b.WriteString("Terminal=false\n")
+	b.WriteString("Categories=Utility;\n")
+	if secs := int(opts.Delay / time.Second); secs > 0 {
+		fmt.Fprintf(&b, "X-GNOME-Autostart-Delay=%d\n", secs)
+	}
+	return b.String()
+}
+
+// desktopExec builds an Exec value as the Desktop Entry spec requires:
+// arguments with reserved characters are double-quoted with ", `, $ and \
+// backslash-escaped, then the value as a whole gets the string escapes, which
+// doubles every backslash, and % is written as %%.
+func desktopExec(argv []string) string {
+	const reserved = " \t\n\"'\\><~|&;$*?#()`"
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && !strings.ContainsAny(arg, reserved) {
+			quoted[i] = arg
+			continue
+		}
+		var b strings.Builder
+		b.WriteByte('"')
+		for _, r := range arg {
+			if strings.ContainsRune("\"`$\\", r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+		quoted[i] = b.String()
+	}
+	value := strings.Join(quoted, " ")
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, "\t", `\t`)
+	return strings.ReplaceAll(value, "%", "%%")
+}
 
-	return os.WriteFile(autostartPath, []byte(desktopFileContent), 0644)
+// desktopUnescape undoes the string escapes and %% of an Exec value, leaving
+// the quoting for firstArg.
+func desktopUnescape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 's':
+				b.WriteByte(' ')
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(value[i])
+			}
+		case c == '%' && i+1 < len(value) && value[i+1] == '%':
+			i++
+			b.WriteByte('%')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// installIcon writes the icon into the hicolor theme of the user's data
+// directory, sized by the PNG's dimensions.
+func installIcon(png []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(png))
+	if err != nil {
+		return err
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	size := fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+	dir := filepath.Join(dataHome, "icons", "hicolor", size, "apps")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, appName+".png"), png, 0644)
 }
 
+// Disable removes both backends.
 func Disable() error {
+	if err := disableXDG(); err != nil {
+		return err
+	}
+	return disableSystemd()
+}
+
+func disableXDG() error {
 	autostartPath, err := getAutostartPath()
 	if err != nil {
 		return err
@@ -66,7 +217,28 @@ func Disable() error {
 	return nil
 }
 
+// IsEnabled reports whether either backend is installed.
 func IsEnabled() (bool, error) {
+	b, err := EnabledBackend()
+	return b != "", err
+}
+
+// EnabledBackend returns the installed backend, or "" if autostart is off.
+func EnabledBackend() (string, error) {
+	enabled, err := isXDGEnabled()
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return BackendXDG, nil
+	}
+	if isSystemdEnabled() {
+		return BackendSystemd, nil
+	}
+	return "", nil
+}
+
+func isXDGEnabled() (bool, error) {
 	autostartPath, err := getAutostartPath()
 	if err != nil {
 		return false, err
@@ -82,3 +254,88 @@ func IsEnabled() (bool, error) {
 	}
 	return false, err
 }
+
+// storedPath returns the executable the installed entry starts.
+func storedPath() (string, bool, error) {
+	b, err := EnabledBackend()
+	if err != nil || b == "" {
+		return "", false, err
+	}
+	if b == BackendSystemd {
+		path, err := getUnitPath()
+		if err != nil {
+			return "", false, err
+		}
+		value, err := readKey(path, "ExecStart")
+		if err != nil {
+			return "", false, err
+		}
+		return systemdUnescape(firstArg(value)), true, nil
+	}
+
+	path, err := getAutostartPath()
+	if err != nil {
+		return "", false, err
+	}
+	value, err := readKey(path, "Exec")
+	if err != nil {
+		return "", false, err
+	}
+	return firstArg(desktopUnescape(value)), true, nil
+}
+
+// rewrite reinstalls whichever backend is installed.
+func rewrite() error {
+	b, err := EnabledBackend()
+	if err != nil {
+		return err
+	}
+	if b == BackendSystemd {
+		return enableSystemd()
+	}
+	return enableXDG()
+}
+
+// readKey returns the value of the first "key=value" line in an ini-style file.
+func readKey(path, key string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no %s in %s", key, path)
+}
+
+// firstArg returns the program of a command line, which may be double-quoted
+// with backslash escapes as the Desktop Entry and systemd formats allow.
+func firstArg(cmdline string) string {
+	if !strings.HasPrefix(cmdline, `"`) {
+		prog, _, _ := strings.Cut(cmdline, " ")
+		return prog
+	}
+	var b strings.Builder
+	for i := 1; i < len(cmdline); i++ {
+		c := cmdline[i]
+		switch {
+		case c == '\\' && i+1 < len(cmdline):
+			i++
+			b.WriteByte(cmdline[i])
+		case c == '"':
+			return b.String()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
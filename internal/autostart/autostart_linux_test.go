@@ -0,0 +1,84 @@
+//go:build linux
+
+package autostart
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirstArg(t *testing.T) {
+	tests := []struct {
+		cmdline, want string
+	}{
+		{"/usr/bin/hydra-reminder", "/usr/bin/hydra-reminder"},
+		{"/usr/bin/hydra-reminder --profile work", "/usr/bin/hydra-reminder"},
+		{`"/opt/Hydra Reminder/hydra-reminder" --profile work`, "/opt/Hydra Reminder/hydra-reminder"},
+		{`"/opt/with \"quote\"/hydra-reminder"`, `/opt/with "quote"/hydra-reminder`},
+	}
+	for _, tt := range tests {
+		if got := firstArg(tt.cmdline); got != tt.want {
+			t.Errorf("firstArg(%q) = %q, want %q", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestDesktopExecRoundTrip(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"/usr/bin/hydra-reminder"}, "/usr/bin/hydra-reminder"},
+		{[]string{"/usr/bin/hydra-reminder", "--profile", "work"}, "/usr/bin/hydra-reminder --profile work"},
+		{[]string{"/opt/Hydra Reminder/hydra-reminder"}, `"/opt/Hydra Reminder/hydra-reminder"`},
+		{[]string{"/opt/a$b/hydra-reminder"}, `"/opt/a\\$b/hydra-reminder"`},
+		{[]string{"/opt/100%/hydra-reminder"}, "/opt/100%%/hydra-reminder"},
+		{[]string{`/opt/back\slash/hydra-reminder`}, `"/opt/back\\\\slash/hydra-reminder"`},
+	}
+	for _, tt := range tests {
+		got := desktopExec(tt.argv)
+		if got != tt.want {
+			t.Errorf("desktopExec(%q) = %q, want %q", tt.argv, got, tt.want)
+		}
+		if prog := firstArg(desktopUnescape(got)); prog != tt.argv[0] {
+			t.Errorf("program of %q = %q, want %q", got, prog, tt.argv[0])
+		}
+	}
+}
+
+func TestSystemdExecRoundTrip(t *testing.T) {
+	for _, exe := range []string{
+		"/usr/bin/hydra-reminder",
+		"/opt/Hydra Reminder/hydra-reminder",
+		`/opt/"quoted" 100% $HOME/hydra-reminder`,
+	} {
+		got := systemdExec([]string{exe, "--profile", "work"})
+		if prog := systemdUnescape(firstArg(got)); prog != exe {
+			t.Errorf("program of %q = %q, want %q", got, prog, exe)
+		}
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	opts := Options{Args: []string{"--profile", "work"}, Delay: 30 * time.Second}
+	entry := desktopEntry("/usr/bin/hydra-reminder", "hydra-reminder", opts)
+	if !strings.Contains(entry, "Exec=/usr/bin/hydra-reminder --startup-delay=30s --profile work\n") {
+		t.Errorf("desktop entry without the delay argument:\n%s", entry)
+	}
+	unit := unitFileContent("/usr/bin/hydra-reminder", opts)
+	if !strings.Contains(unit, `ExecStart="/usr/bin/hydra-reminder" "--startup-delay=30s" "--profile" "work"`) {
+		t.Errorf("unit without the delay argument:\n%s", unit)
+	}
+	if !strings.Contains(entry, "X-GNOME-Autostart-Delay=30\n") {
+		t.Errorf("desktop entry without X-GNOME-Autostart-Delay:\n%s", entry)
+	}
+	if strings.Contains(unit, "ExecStartPre") {
+		t.Errorf("delay left to systemd, it would apply on every restart:\n%s", unit)
+	}
+
+	entry = desktopEntry("/usr/bin/hydra-reminder", "hydra-reminder", Options{})
+	if strings.Contains(entry, "X-GNOME-Autostart-Delay") {
+		t.Errorf("delay key without a delay:\n%s", entry)
+	}
+}
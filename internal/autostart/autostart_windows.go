@@ -3,21 +3,30 @@
 package autostart
 
 import (
-	"os"
-	"path/filepath"
+	"fmt"
+	"log/slog"
+	"strings"
+	"syscall"
 
 	"golang.org/x/sys/windows/registry"
 )
 
 const appName = "HydraReminder"
 
-func Enable() error {
-	exePath, err := os.Executable()
-	if err != nil {
-		return err
+// BackendRegistry uses the Run key of the current user, the only backend on Windows.
+const BackendRegistry = "registry"
+
+// SetBackend selects what Enable installs. Windows only supports BackendRegistry,
+// an empty name selects it too.
+func SetBackend(name string) error {
+	if name != "" && name != BackendRegistry {
+		return fmt.Errorf("unsupported autostart backend %q on windows", name)
 	}
+	return nil
+}
 
-	exePath, err = filepath.Abs(exePath)
+func Enable() error {
+	exePath, err := executablePath()
 	if err != nil {
 		return err
 	}
@@ -28,7 +37,16 @@ func Enable() error {
 	}
 	defer k.Close()
 
-	return k.SetStringValue(appName, `"`+exePath+`"`)
+	argv := options.argv(exePath)
+	value := `"` + argv[0] + `"`
+	for _, arg := range argv[1:] {
+		value += " " + syscall.EscapeArg(arg)
+	}
+	if err := k.SetStringValue(appName, value); err != nil {
+		return err
+	}
+	slog.Debug("Autostart registry value set", "value", value)
+	return nil
 }
 
 func Disable() error {
@@ -45,6 +63,15 @@ func Disable() error {
 	return nil
 }
 
+// EnabledBackend returns BackendRegistry if autostart is on, "" otherwise.
+func EnabledBackend() (string, error) {
+	enabled, err := IsEnabled()
+	if !enabled {
+		return "", err
+	}
+	return BackendRegistry, nil
+}
+
 func IsEnabled() (bool, error) {
 	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.QUERY_VALUE)
 	if err != nil {
@@ -62,3 +89,33 @@ func IsEnabled() (bool, error) {
 
 	return true, nil
 }
+
+// storedPath returns the executable the Run value starts.
+func storedPath() (string, bool, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.QUERY_VALUE)
+	if err != nil {
+		return "", false, err
+	}
+	defer k.Close()
+
+	value, _, err := k.GetStringValue(appName)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	// The value is "C:\path\app.exe" possibly followed by arguments.
+	if strings.HasPrefix(value, `"`) {
+		if end := strings.Index(value[1:], `"`); end >= 0 {
+			return value[1 : end+1], true, nil
+		}
+	}
+	prog, _, _ := strings.Cut(value, " ")
+	return prog, true, nil
+}
+
+func rewrite() error {
+	return Enable()
+}
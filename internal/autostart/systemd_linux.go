@@ -0,0 +1,157 @@
+//go:build linux
+
+package autostart
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	unitName = appName + ".service"
+
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	systemdManager = "org.freedesktop.systemd1.Manager"
+)
+
+func getUnitPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "systemd", "user", unitName), nil
+}
+
+func unitFileContent(exePath string, opts Options) string {
+	return fmt.Sprintf(`[Unit]
+Description=Hydra Reminder
+Documentation=https://github.com/PaulEhlers/hydra-reminder
+PartOf=graphical-session.target
+After=graphical-session.target
+
+[Service]
+ExecStart=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=graphical-session.target
+`, systemdExec(opts.argv(exePath)))
+}
+
+// systemdExec quotes a command line for ExecStart: every argument in double
+// quotes with C-style escapes, and % and $ doubled so systemd does not expand
+// specifiers or variables.
+func systemdExec(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		arg = strings.ReplaceAll(arg, `\`, `\\`)
+		arg = strings.ReplaceAll(arg, `"`, `\"`)
+		arg = strings.ReplaceAll(arg, "%", "%%")
+		arg = strings.ReplaceAll(arg, "$", "$$")
+		quoted[i] = `"` + arg + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdUnescape undoes the %% and $$ doubling of systemdExec.
+func systemdUnescape(s string) string {
+	s = strings.ReplaceAll(s, "%%", "%")
+	return strings.ReplaceAll(s, "$$", "$")
+}
+
+// systemdUser returns the systemd user manager, reachable on the session bus.
+func systemdUser() (dbus.BusObject, func(), error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn.Object(systemdDest, systemdPath), func() { conn.Close() }, nil
+}
+
+// enableSystemd writes the unit and enables it. The unit is not started, this
+// process already is the running instance.
+func enableSystemd() error {
+	exePath, err := executablePath()
+	if err != nil {
+		return err
+	}
+	unitPath, err := getUnitPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(unitPath, []byte(unitFileContent(exePath, options)), 0644); err != nil {
+		return err
+	}
+
+	manager, closeConn, err := systemdUser()
+	if err != nil {
+		return fmt.Errorf("connecting to systemd: %w", err)
+	}
+	defer closeConn()
+
+	if err := manager.Call(systemdManager+".Reload", 0).Err; err != nil {
+		return fmt.Errorf("reloading systemd: %w", err)
+	}
+	var carriesInstallInfo bool
+	var changes [][]any
+	err = manager.Call(systemdManager+".EnableUnitFiles", 0, []string{unitName}, false, true).
+		Store(&carriesInstallInfo, &changes)
+	if err != nil {
+		return fmt.Errorf("enabling %s: %w", unitName, err)
+	}
+	slog.Debug("Autostart unit enabled", "path", unitPath, "changes", changes)
+	return nil
+}
+
+// disableSystemd disables and removes the unit if it is installed. It does not
+// stop the unit, which may be this very process.
+func disableSystemd() error {
+	unitPath, err := getUnitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	manager, closeConn, err := systemdUser()
+	if err != nil {
+		return fmt.Errorf("connecting to systemd: %w", err)
+	}
+	defer closeConn()
+
+	var changes [][]any
+	if err := manager.Call(systemdManager+".DisableUnitFiles", 0, []string{unitName}, false).Store(&changes); err != nil {
+		return fmt.Errorf("disabling %s: %w", unitName, err)
+	}
+	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return manager.Call(systemdManager+".Reload", 0).Err
+}
+
+// isSystemdEnabled asks systemd for the unit's state. Without a reachable
+// user manager the unit cannot be enabled either.
+func isSystemdEnabled() bool {
+	manager, closeConn, err := systemdUser()
+	if err != nil {
+		return false
+	}
+	defer closeConn()
+
+	var state string
+	if err := manager.Call(systemdManager+".GetUnitFileState", 0, unitName).Store(&state); err != nil {
+		return false
+	}
+	return state == "enabled"
+}
@@ -0,0 +1,154 @@
+// Package breaks follows the breaks taken after alerts while break tracking
+// is on: it records how long each one lasted in the history, ends a break
+// when the user is back at the keyboard, and warns when breaks keep falling
+// short of the configured minimum.
+package breaks
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/timer"
+)
+
+const (
+	// grace is how long input right after acknowledging does not count as a
+	// return, the user may still be letting go of the mouse.
+	grace        = 15 * time.Second
+	pollInterval = time.Second
+
+	// window is how many recent breaks the warning looks at, shortLimit of
+	// them being too short warns.
+	window     = 5
+	shortLimit = 4
+)
+
+// Tracker records breaks and detects returns from them.
+type Tracker struct {
+	tm  *timer.Manager
+	cfg *config.Live
+	log *history.Log // nil without a history
+
+	mu     sync.Mutex
+	recent []time.Duration // the last window breaks, oldest first
+	warned bool
+}
+
+// New returns a tracker for tm, following the break settings currently in
+// cfg, that records breaks in log, which may be nil, and starts from the
+// recent breaks in it. Subscribe Observe to tm and start Run to use it.
+func New(tm *timer.Manager, cfg *config.Live, log *history.Log) *Tracker {
+	t := &Tracker{tm: tm, cfg: cfg, log: log}
+	if log == nil {
+		return t
+	}
+	for _, e := range log.Recent(window, history.KindBreak) {
+		t.recent = append(t.recent, time.Duration(e.Seconds)*time.Second)
+	}
+	return t
+}
+
+// Observe records the breaks that end. It is meant for
+// timer.Manager.Subscribe and does not block.
+func (t *Tracker) Observe(ev timer.Event) {
+	if ev.Kind != timer.EventReturn {
+		return
+	}
+
+	t.mu.Lock()
+	t.recent = append(t.recent, ev.Break)
+	if len(t.recent) > window {
+		t.recent = t.recent[len(t.recent)-window:]
+	}
+	warning := t.warning()
+	if warning != "" && !t.warned {
+		slog.Warn("Breaks are too short", "detail", warning)
+	}
+	t.warned = warning != ""
+	t.mu.Unlock()
+
+	if t.log == nil {
+		return
+	}
+	e := history.Entry{
+		At:      ev.At.UTC().Truncate(time.Second),
+		Kind:    history.KindBreak,
+		Seconds: int(ev.Break.Round(time.Second).Seconds()),
+		Profile: t.cfg.Load().ActiveProfile,
+	}
+	go func() {
+		if err := t.log.Append(e); err != nil {
+			slog.Error("Failed to record break", "err", err)
+		}
+	}()
+}
+
+// Warning describes how recent breaks fell short of the minimum, or returns
+// "" if they did not or break tracking is off.
+func (t *Tracker) Warning() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.warning()
+}
+
+// Internal func, assumes lock is held
+func (t *Tracker) warning() string {
+	settings := t.cfg.Load().Breaks
+	if !settings.Enabled || len(t.recent) < window {
+		return ""
+	}
+	minimum := settings.Minimum()
+	short := 0
+	for _, d := range t.recent {
+		if d < minimum {
+			short++
+		}
+	}
+	if short < shortLimit {
+		return ""
+	}
+	return fmt.Sprintf("%d of your last %d breaks were shorter than %d min", short, window, settings.MinimumMinutes)
+}
+
+// Run ends breaks on keyboard or mouse input once the grace period is over,
+// if the breaks settings' detect_return is on, until stop is closed. A nil
+// stop runs until the process exits.
+func (t *Tracker) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	reported := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if settings := t.cfg.Load().Breaks; !settings.Enabled || !settings.DetectReturn || t.tm.GetState() != timer.StateBreak {
+			continue
+		}
+		elapsed := t.tm.BreakElapsed()
+		if elapsed < grace {
+			continue
+		}
+		idle, err := idleTime()
+		if err != nil {
+			if !reported {
+				slog.Warn("Cannot detect returns from breaks, reset to end them", "err", err)
+				reported = true
+			}
+			continue
+		}
+		// Any input after the grace period, also between two polls.
+		if idle < elapsed-grace {
+			t.tm.EndBreak()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package breaks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/history"
+	"hydra-reminder/internal/timer"
+)
+
+func newTestTracker(t *testing.T) (*Tracker, *config.Live) {
+	t.Helper()
+	config.SetDir(t.TempDir())
+	t.Cleanup(func() { config.SetDir("") })
+
+	log, err := history.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Breaks.Enabled = true
+	live := config.NewLive(cfg)
+	return New(timer.NewManager(nil, nil, nil, nil), live, log), live
+}
+
+func returned(after time.Duration) timer.Event {
+	return timer.Event{Kind: timer.EventReturn, From: timer.StateBreak, At: time.Now(), Break: after}
+}
+
+func TestWarnsAboutShortBreaks(t *testing.T) {
+	tr, live := newTestTracker(t)
+
+	for _, d := range []time.Duration{30 * time.Second, 5 * time.Minute, time.Minute, 90 * time.Second} {
+		tr.Observe(returned(d))
+	}
+	if w := tr.Warning(); w != "" {
+		t.Errorf("warning after four breaks: %q", w)
+	}
+	tr.Observe(returned(45 * time.Second))
+	if w := tr.Warning(); !strings.Contains(w, "4 of your last 5") {
+		t.Errorf("Warning() = %q, want 4 of 5 too short", w)
+	}
+
+	on := live.Load()
+	off := on.Clone()
+	off.Breaks.Enabled = false
+	live.Store(off)
+	if w := tr.Warning(); w != "" {
+		t.Errorf("warning with break tracking off: %q", w)
+	}
+	live.Store(on)
+
+	// Two long breaks push the short ones out of the window.
+	tr.Observe(returned(3 * time.Minute))
+	tr.Observe(returned(10 * time.Minute))
+	if w := tr.Warning(); w != "" {
+		t.Errorf("warning after long breaks: %q", w)
+	}
+}
+
+func TestBreaksSurviveRestart(t *testing.T) {
+	tr, live := newTestTracker(t)
+	for range window {
+		tr.Observe(returned(20 * time.Second))
+	}
+
+	// Appends happen in the background.
+	var log *history.Log
+	deadline := time.Now().Add(time.Second)
+	for {
+		var err error
+		log, err = history.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(log.Recent(window, history.KindBreak)) == window || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	entries := log.Recent(window, history.KindBreak)
+	if len(entries) != window || entries[0].Seconds != 20 {
+		t.Fatalf("history = %+v, want %d breaks of 20s", entries, window)
+	}
+
+	restarted := New(timer.NewManager(nil, nil, nil, nil), live, log)
+	if w := restarted.Warning(); w == "" {
+		t.Error("no warning after a restart, want the recorded breaks counted")
+	}
+}
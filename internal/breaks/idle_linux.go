@@ -0,0 +1,58 @@
+//go:build linux
+
+package breaks
+
+/*
+#cgo LDFLAGS: -lX11 -lXss
+
+#include <X11/Xlib.h>
+#include <X11/extensions/scrnsaver.h>
+
+// idleMs returns the milliseconds since the last keyboard or mouse input, or
+// -1 when the server has no MIT-SCREEN-SAVER extension.
+static long idleMs(Display *dpy) {
+	int event, error;
+	if (!XScreenSaverQueryExtension(dpy, &event, &error)) {
+		return -1;
+	}
+	XScreenSaverInfo *info = XScreenSaverAllocInfo();
+	if (info == NULL) {
+		return -1;
+	}
+	XScreenSaverQueryInfo(dpy, DefaultRootWindow(dpy), info);
+	long ms = info->idle;
+	XFree(info);
+	return ms;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	idleMu  sync.Mutex
+	idleDpy *C.Display
+)
+
+// idleTime returns how long there was no keyboard or mouse input. It needs an
+// X server, under Wayland only XWayland clients' input is seen, if any.
+func idleTime() (time.Duration, error) {
+	idleMu.Lock()
+	defer idleMu.Unlock()
+
+	if idleDpy == nil {
+		idleDpy = C.XOpenDisplay(nil)
+		if idleDpy == nil {
+			return 0, errors.New("cannot open X display")
+		}
+	}
+	ms := C.idleMs(idleDpy)
+	if ms < 0 {
+		return 0, errors.New("X server has no MIT-SCREEN-SAVER extension")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
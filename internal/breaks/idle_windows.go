@@ -0,0 +1,35 @@
+//go:build windows
+
+package breaks
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32               = windows.NewLazySystemDLL("user32.dll")
+	kernel32             = windows.NewLazySystemDLL("kernel32.dll")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+	procGetTickCount     = kernel32.NewProc("GetTickCount")
+)
+
+// lastInputInfo is LASTINPUTINFO.
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+// idleTime returns how long there was no keyboard or mouse input in the session.
+func idleTime() (time.Duration, error) {
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, err
+	}
+	now, _, _ := procGetTickCount.Call()
+	// Both are milliseconds since boot and wrap after 49.7 days, the
+	// unsigned difference is right across the wrap.
+	return time.Duration(uint32(now)-info.dwTime) * time.Millisecond, nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// BundleFormat identifies a settings bundle written by Export.
+const BundleFormat = "hydra-reminder-settings"
+
+// machineFields describe this computer rather than the user's preferences and
+// are left out of bundles. Import keeps the local values for them.
+// Hooks, webhooks and MQTT are left out too, importing a bundle must not make
+// this computer run someone else's commands or send events, and webhook
+// headers and the MQTT password are credentials.
+var machineFields = []string{"autostart", "autostart_backend", "autostart_args", "autostart_delay_seconds", "http_enabled", "http_port", "hooks", "webhooks", "mqtt"}
+
+// Bundle is a shareable file holding the config, its profiles and hotkeys.
+type Bundle struct {
+	Format     string          `json:"format"`
+	Version    int             `json:"version"` // config schema version of Config
+	ExportedAt time.Time       `json:"exported_at"`
+	Config     json.RawMessage `json:"config"`
+}
+
+// Export returns cfg as a bundle without machine-specific settings.
+func Export(cfg *Config) ([]byte, error) {
+	doc, err := toDoc(cfg.withActiveProfileSynced())
+	if err != nil {
+		return nil, err
+	}
+	delete(doc, "version")
+	for _, f := range machineFields {
+		delete(doc, f)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(Bundle{
+		Format:     BundleFormat,
+		Version:    CurrentVersion,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Config:     raw,
+	}, "", "  ")
+}
+
+// Import returns a copy of current with the settings from a bundle applied.
+// Bundles from older versions are migrated, machine-specific settings keep
+// their current values. The result is validated.
+func Import(data []byte, current *Config) (*Config, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+	if b.Format != BundleFormat {
+		return nil, fmt.Errorf("not a settings bundle (format %q)", b.Format)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(b.Config, &doc); err != nil {
+		return nil, fmt.Errorf("reading bundle config: %w", err)
+	}
+	for _, f := range machineFields {
+		delete(doc, f)
+	}
+	doc["version"] = b.Version
+	versioned, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	migrated, _, err := migrate(versioned)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *current
+	// The bundle's profiles replace the local ones rather than merge with them.
+	out.Profiles = nil
+	if err := json.Unmarshal(migrated, &out); err != nil {
+		return nil, fmt.Errorf("reading bundle config: %w", err)
+	}
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Change is one setting that differs between two configs.
+type Change struct {
+	Field    string // JSON path, e.g. profiles.focus.duration_minutes
+	Old, New any    // nil when the field is missing on that side
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s = %s", c.Field, formatValue(c.New))
+	case c.New == nil:
+		return fmt.Sprintf("- %s (was %s)", c.Field, formatValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Field, formatValue(c.Old), formatValue(c.New))
+}
+
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Diff lists the settings that differ between a and b, sorted by field.
+func Diff(a, b *Config) ([]Change, error) {
+	docA, err := toDoc(a.withActiveProfileSynced())
+	if err != nil {
+		return nil, err
+	}
+	docB, err := toDoc(b.withActiveProfileSynced())
+	if err != nil {
+		return nil, err
+	}
+	delete(docA, "version")
+	delete(docB, "version")
+
+	var changes []Change
+	diffDocs("", docA, docB, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func diffDocs(prefix string, a, b map[string]any, changes *[]Change) {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		field := prefix + k
+		mapA, aIsMap := va.(map[string]any)
+		mapB, bIsMap := vb.(map[string]any)
+		switch {
+		case aIsMap && bIsMap:
+			diffDocs(field+".", mapA, mapB, changes)
+		case !inA:
+			*changes = append(*changes, Change{Field: field, New: vb})
+		case !inB:
+			*changes = append(*changes, Change{Field: field, Old: va})
+		case !reflect.DeepEqual(va, vb):
+			*changes = append(*changes, Change{Field: field, Old: va, New: vb})
+		}
+	}
+}
+
+// toDoc turns cfg into the generic form it has on disk.
+func toDoc(cfg *Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
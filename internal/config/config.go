@@ -2,22 +2,164 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
+// CurrentVersion is the schema version written by Save. Files with an older
+// version are upgraded by Load, see migrate.go.
+const CurrentVersion = 2
+
 type Config struct {
-	DurationMinutes int    `json:"duration_minutes"`
-	AlertColor      string `json:"alert_color"`
-	AlertStyle      string `json:"alert_style"` // "color" or "blink"
-	HotkeyEnabled   bool   `json:"hotkey_enabled"`
-	HotkeyModifiers uint32 `json:"hotkey_modifiers"` // See win32 MOD_ALT, MOD_CONTROL etc
-	HotkeyResetKey  uint32 `json:"hotkey_reset_key"` // Virtual key code for reset
-	Autostart       bool   `json:"autostart"`
+	Version          int    `json:"version"`
+	DurationMinutes  int    `json:"duration_minutes"`
+	AlertColor       string `json:"alert_color"`
+	AlertStyle       string `json:"alert_style"` // "color" or "blink"
+	HotkeyEnabled    bool   `json:"hotkey_enabled"`
+	HotkeyModifiers  uint32 `json:"hotkey_modifiers"` // See win32 MOD_ALT, MOD_CONTROL etc
+	HotkeyResetKey   uint32 `json:"hotkey_reset_key"` // Virtual key code for reset
+	ChordEnabled     bool   `json:"chord_enabled"`
+	ChordLeaderKey   uint32 `json:"chord_leader_key"` // Virtual key code pressed with HotkeyModifiers to start a chord
+	ChordTimeoutMs   int    `json:"chord_timeout_ms"` // How long to wait for the action key after the leader
+	SnoozeMinutes    int    `json:"snooze_minutes"`
+	Autostart        bool   `json:"autostart"`
+	AutostartBackend string `json:"autostart_backend"` // "" for the platform default, "xdg" or "systemd" on Linux
+	// AutostartArgs are passed to the app when it is started at login, e.g. ["--profile", "work"].
+	AutostartArgs         []string `json:"autostart_args"`
+	AutostartDelaySeconds int      `json:"autostart_delay_seconds"` // Wait after login so the tray host is ready
+	LogLevel              string   `json:"log_level"`               // "debug", "info", "warn" or "error"
+
+	// Actions for interacting with the tray icon itself, see TrayActions.
+	TrayLeftClick     string `json:"tray_left_click"`
+	TrayMiddleClick   string `json:"tray_middle_click"`
+	TrayScrollUp      string `json:"tray_scroll_up"`
+	TrayScrollDown    string `json:"tray_scroll_down"`
+	TrayMenuOpen      string `json:"tray_menu_open"`      // Right click, or any click on hosts that always show the menu
+	ScrollStepMinutes int    `json:"scroll_step_minutes"` // Time added or removed per scroll step
+
+	HTTPEnabled bool `json:"http_enabled"` // Local HTTP API on 127.0.0.1
+	HTTPPort    int  `json:"http_port"`
+
+	Pomodoro Pomodoro `json:"pomodoro"`
+	Breaks   Breaks   `json:"breaks"`
+	Adaptive Adaptive `json:"adaptive"`
+
+	AlertLimit AlertLimit `json:"alert_limit"`
+
+	Hooks    Hooks     `json:"hooks"`
+	Webhooks []Webhook `json:"webhooks"`
+	MQTT     MQTT      `json:"mqtt"`
+
+	// Profiles are named sets of reminder settings. The top-level fields above
+	// are always the active ones, switching copies a profile over them and
+	// saving copies them back into the active profile.
+	Profiles      map[string]Profile `json:"profiles"`
+	ActiveProfile string             `json:"active_profile"`
+}
+
+// Pomodoro replaces the repeating interval with work phases and breaks.
+type Pomodoro struct {
+	Enabled               bool `json:"enabled"`
+	WorkMinutes           int  `json:"work_minutes"`
+	ShortBreakMinutes     int  `json:"short_break_minutes"`
+	LongBreakMinutes      int  `json:"long_break_minutes"`
+	CyclesBeforeLongBreak int  `json:"cycles_before_long_break"`
+	// AutoAdvance starts the next phase when one ends instead of alerting and
+	// waiting for a reset.
+	AutoAdvance bool `json:"auto_advance"`
+}
+
+// Durations returns the lengths of the work phase and the two breaks.
+func (p Pomodoro) Durations() (work, shortBreak, longBreak time.Duration) {
+	return time.Duration(p.WorkMinutes) * time.Minute,
+		time.Duration(p.ShortBreakMinutes) * time.Minute,
+		time.Duration(p.LongBreakMinutes) * time.Minute
+}
+
+// Breaks tracks the break taken after each alert: acknowledging it starts
+// the break, and the next countdown only starts once the user returns.
+type Breaks struct {
+	Enabled bool `json:"enabled"`
+	// MinimumMinutes is the break length below which recent breaks count as
+	// too short and get a warning.
+	MinimumMinutes int `json:"minimum_minutes"`
+	// DetectReturn ends the break on keyboard or mouse input, not only on a
+	// reset from the hotkey, the tray or the control socket.
+	DetectReturn bool `json:"detect_return"`
+}
+
+// Minimum returns MinimumMinutes as a duration.
+func (b Breaks) Minimum() time.Duration {
+	return time.Duration(b.MinimumMinutes) * time.Minute
+}
+
+// Adaptive suggests a new interval from how recent alerts were answered,
+// see internal/adaptive.
+type Adaptive struct {
+	Enabled bool `json:"enabled"`
+	// Auto applies the suggestion instead of only showing it in the menu.
+	Auto bool `json:"auto"`
+	// MinMinutes and MaxMinutes bound the suggested interval.
+	MinMinutes int `json:"min_minutes"`
+	MaxMinutes int `json:"max_minutes"`
+}
+
+// AlertLimit keeps alerts from nagging: alerts due within MinGapMinutes of
+// the last one are merged into it, and at most MaxPerDay are delivered a
+// day. Held back alerts start the next countdown as if acknowledged. 0 turns
+// either limit off.
+type AlertLimit struct {
+	MaxPerDay     int `json:"max_per_day"`
+	MinGapMinutes int `json:"min_gap_minutes"`
+}
+
+// Hooks are shell commands run when the timer changes state, e.g.
+// "notify-send 'Drink water'" on alert. Empty commands are skipped.
+type Hooks struct {
+	OnAlert string `json:"on_alert"`
+	OnStart string `json:"on_start"`
+	OnStop  string `json:"on_stop"`
+	OnPause string `json:"on_pause"`
+}
+
+// Webhook is an HTTP request sent on timer events, see internal/webhook.
+type Webhook struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"` // POST if empty
+	Headers map[string]string `json:"headers"`
+	// Template is a Go text/template rendering the JSON body, empty for the
+	// default body. See internal/webhook for the fields it can use.
+	Template string `json:"template"`
+	// Events are the events that fire the webhook, all of WebhookEvents if empty.
+	Events []string `json:"events"`
+}
+
+// WebhookEvents are the events webhooks can fire on.
+var WebhookEvents = []string{"alert", "reset", "snooze"}
+
+// MQTT connects the app to a broker for smart-home setups, see internal/mqtt.
+// It is off while Broker is empty.
+type MQTT struct {
+	Broker   string `json:"broker"` // e.g. tcp://homeassistant.local:1883, ssl:// for TLS
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ClientID string `json:"client_id"` // "hydra-reminder-<hostname>" if empty
+	// StateTopic gets the timer status as retained JSON, CommandTopic takes
+	// "reset", "stop", "snooze" and the other ctl commands.
+	StateTopic   string `json:"state_topic"`
+	CommandTopic string `json:"command_topic"`
+	// DiscoveryPrefix is Home Assistant's MQTT discovery prefix, empty to not
+	// announce the app as a device.
+	DiscoveryPrefix string `json:"discovery_prefix"`
 }
 
 func DefaultConfig() *Config {
-	return &Config{
+	cfg := &Config{
+		Version:         CurrentVersion,
 		DurationMinutes: 30,
 		AlertColor:      "#FF0000",
 		AlertStyle:      "color",
@@ -25,56 +167,330 @@ func DefaultConfig() *Config {
 		// CTRL + ALT + R
 		HotkeyModifiers: 0x0002 | 0x0001, // MOD_CONTROL | MOD_ALT
 		HotkeyResetKey:  0x52,            // 'R'
-		Autostart:       false,
+		ChordEnabled:    false,
+		// CTRL + ALT + H, then R/S/D/P
+		ChordLeaderKey: 0x48, // 'H'
+		ChordTimeoutMs: 1500,
+		SnoozeMinutes:  5,
+		Autostart:      false,
+		LogLevel:       "info",
+		// Left click resets, middle click pauses, scrolling adjusts the time left
+		TrayLeftClick:     "reset",
+		TrayMiddleClick:   "pause",
+		TrayScrollUp:      "add_time",
+		TrayScrollDown:    "subtract_time",
+		TrayMenuOpen:      "acknowledge",
+		ScrollStepMinutes: 5,
+		HTTPEnabled:       false,
+		HTTPPort:          7878,
+		Pomodoro: Pomodoro{
+			WorkMinutes:           25,
+			ShortBreakMinutes:     5,
+			LongBreakMinutes:      15,
+			CyclesBeforeLongBreak: 4,
+		},
+		Breaks: Breaks{
+			MinimumMinutes: 2,
+			DetectReturn:   true,
+		},
+		Adaptive: Adaptive{
+			MinMinutes: 15,
+			MaxMinutes: 120,
+		},
+		MQTT: MQTT{
+			StateTopic:      "hydra-reminder/state",
+			CommandTopic:    "hydra-reminder/command",
+			DiscoveryPrefix: "homeassistant",
+		},
 	}
+	cfg.Profiles = map[string]Profile{DefaultProfile: cfg.currentProfile()}
+	cfg.ActiveProfile = DefaultProfile
+	return cfg
 }
 
-func GetConfigPath() (string, error) {
+// Duration is the reminder interval. A DurationMinutes of 0 is the 10 second debug interval.
+func (c *Config) Duration() time.Duration {
+	if c.DurationMinutes == 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.DurationMinutes) * time.Minute
+}
+
+// PortableMarker is the file that, placed next to the executable, switches to
+// portable mode: config and state live in a folder beside the executable
+// instead of the user's config directory.
+const PortableMarker = "hydra-reminder.portable"
+
+const appDirName = "HydraReminder"
+
+var (
+	// pathOverride replaces the config file location, see SetPath.
+	pathOverride string
+	// dirOverride replaces the config and state directory, see SetDir.
+	dirOverride string
+)
+
+// SetPath makes Load, Save and GetConfigPath use path instead of config.json
+// in Dir. State files still go to Dir.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// SetDir makes Dir return dir, for --config-dir and HYDRA_CONFIG_DIR.
+func SetDir(dir string) {
+	dirOverride = dir
+}
+
+// Dir returns the directory for config and state files. In order of
+// precedence it is the one given to SetDir, the portable folder next to the
+// executable when PortableMarker exists there, or HydraReminder in the user's
+// config directory. Dir does not create the directory, writers do.
+func Dir() (string, error) {
+	if dirOverride != "" {
+		return filepath.Abs(dirOverride)
+	}
+	if dir, ok := portableDir(); ok {
+		return dir, nil
+	}
 	appData, err := os.UserConfigDir()
 	if err != nil {
 		return "", err
 	}
-	dir := filepath.Join(appData, "HydraReminder")
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	return filepath.Join(appData, appDirName), nil
+}
+
+// IsPortable reports whether Dir is the portable folder next to the executable.
+func IsPortable() bool {
+	if dirOverride != "" {
+		return false
+	}
+	_, ok := portableDir()
+	return ok
+}
+
+func portableDir() (string, bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	exeDir := filepath.Dir(exe)
+	if _, err := os.Stat(filepath.Join(exeDir, PortableMarker)); err != nil {
+		return "", false
+	}
+	return filepath.Join(exeDir, appDirName), true
+}
+
+func GetConfigPath() (string, error) {
+	if pathOverride != "" {
+		return filepath.Abs(pathOverride)
+	}
+	dir, err := Dir()
+	if err != nil {
 		return "", err
 	}
 	return filepath.Join(dir, "config.json"), nil
 }
 
+// Load reads the config file, creating it with defaults if it does not exist.
+// When the file cannot be used as is, Load returns a usable config together
+// with a *LoadError describing what was wrong.
 func Load() (*Config, error) {
 	path, err := GetConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	return loadFile(path)
+}
 
+func loadFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			cfg := DefaultConfig()
-			_ = Save(cfg)
+			_ = saveFile(path, cfg)
 			return cfg, nil
 		}
 		return nil, err
 	}
 
-	cfg := DefaultConfig()
-	if err := json.Unmarshal(data, cfg); err != nil {
+	cfg, from, err := decode(data)
+	if err != nil {
+		return invalidFile(path, err, DefaultConfig())
+	}
+
+	if err := cfg.Validate(); err != nil {
+		// The original file is kept as .invalid, no separate migration backup needed.
+		verr := err.(*ValidationError)
+		cfg.applyFallbacks(verr)
+		return invalidFile(path, verr, cfg)
+	}
+
+	if from < CurrentVersion {
+		// Keep the file as it was before the upgrade, an older release can
+		// still read it and the user has something to go back to.
+		backup := fmt.Sprintf("%s.v%d.bak", path, from)
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			if err := WriteFileAtomic(backup, data, filePerm); err != nil {
+				return nil, fmt.Errorf("backing up config before migration: %w", err)
+			}
+		}
+		if err := saveFile(path, cfg); err != nil {
+			return nil, err
+		}
+		slog.Info("Migrated config", "from", from, "to", CurrentVersion, "backup", backup)
+	}
+
+	return cfg, nil
+}
+
+// Peek reads the config file like Load but leaves it alone, for commands that
+// only look at the settings: a missing file gives the defaults, an older
+// version is migrated in memory only, and invalid values are reset and
+// reported in a *LoadError without moving the file aside.
+func Peek() (*Config, error) {
+	path, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return peekFile(path)
+}
+
+func peekFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
 		return nil, err
 	}
 
+	cfg, _, err := decode(data)
+	if err != nil {
+		return DefaultConfig(), &LoadError{Err: err}
+	}
+	if err := cfg.Validate(); err != nil {
+		verr := err.(*ValidationError)
+		cfg.applyFallbacks(verr)
+		return cfg, &LoadError{Err: verr}
+	}
 	return cfg, nil
 }
 
+// decode parses a config file of any supported version on top of the defaults
+// and returns it with the version the file was written in.
+func decode(data []byte) (*Config, int, error) {
+	migrated, from, err := migrate(data)
+	if err != nil {
+		return nil, from, err
+	}
+
+	cfg := DefaultConfig()
+	// Profiles come from the file only, decoding into the default map would
+	// bring back profiles the user deleted.
+	cfg.Profiles = nil
+	cfg.ActiveProfile = ""
+	if err := json.Unmarshal(migrated, cfg); err != nil {
+		return nil, from, err
+	}
+	return cfg, from, nil
+}
+
+// invalidFile moves the file at path to path+".invalid" so the user's edits are
+// never overwritten, and writes the config that is used instead in its place.
+// A copy kept from an earlier invalid file stays, the new one gets a number,
+// e.g. config.json.invalid.2.
+func invalidFile(path string, cause error, cfg *Config) (*Config, error) {
+	loadErr := &LoadError{Err: cause}
+	moved := path + ".invalid"
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(moved); os.IsNotExist(err) {
+			break
+		}
+		moved = fmt.Sprintf("%s.invalid.%d", path, n)
+	}
+	if err := os.Rename(path, moved); err != nil {
+		slog.Error("Failed to move invalid config aside", "err", err)
+		// Without a copy of the original, leave it alone rather than overwrite it.
+		return cfg, loadErr
+	}
+	loadErr.Moved = moved
+	if err := os.Chmod(moved, filePerm); err != nil {
+		slog.Warn("Failed to restrict permissions of the invalid config", "err", err)
+	}
+	if err := saveFile(path, cfg); err != nil {
+		slog.Error("Failed to write fallback config", "err", err)
+	}
+	return cfg, loadErr
+}
+
 func Save(cfg *Config) error {
 	path, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
+	return saveFile(path, cfg)
+}
+
+// filePerm keeps config.json and its copies private to the user, the file
+// holds credentials such as mqtt.password and webhook headers.
+const filePerm = 0600
+
+// saveMu makes saves from concurrent menu goroutines happen one after another.
+var saveMu sync.Mutex
+
+func saveFile(path string, cfg *Config) error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	cfg.Version = CurrentVersion
+	cfg = cfg.withActiveProfileSynced()
 
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Remember before the rename so the watcher never sees an unknown write.
+	rememberWrite(path, data)
+	return WriteFileAtomic(path, data, filePerm)
+}
+
+// WriteFileAtomic replaces path with data so that readers, and the file left
+// behind after a crash or a full disk, see either the old or the new content.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// Removing fails harmlessly once the rename has succeeded.
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
 }
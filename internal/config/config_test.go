@@ -0,0 +1,283 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// copyFixture copies testdata/name into a temp dir as config.json.
+func copyFixture(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestLoadMigratesHistoricalShapes(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		fromVersion int
+		check       func(t *testing.T, cfg *Config)
+	}{
+		{
+			fixture:     "v0_initial.json",
+			fromVersion: 0,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DurationMinutes != 45 || cfg.AlertStyle != "blink" || !cfg.HotkeyEnabled ||
+					cfg.HotkeyModifiers != 6 || cfg.HotkeyResetKey != 'K' || !cfg.Autostart {
+					t.Errorf("original fields not preserved: %+v", cfg)
+				}
+				def := DefaultConfig()
+				if cfg.ChordLeaderKey != def.ChordLeaderKey || cfg.ChordTimeoutMs != def.ChordTimeoutMs ||
+					cfg.SnoozeMinutes != def.SnoozeMinutes {
+					t.Errorf("missing fields did not fall back to defaults: %+v", cfg)
+				}
+				checkDefaultProfile(t, cfg)
+			},
+		},
+		{
+			fixture:     "v0_chord.json",
+			fromVersion: 0,
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.ChordEnabled || cfg.ChordLeaderKey != 'J' || cfg.ChordTimeoutMs != 2000 ||
+					cfg.SnoozeMinutes != 10 || cfg.DurationMinutes != 15 {
+					t.Errorf("chord fields not preserved: %+v", cfg)
+				}
+				checkDefaultProfile(t, cfg)
+			},
+		},
+		{
+			fixture:     "v1.json",
+			fromVersion: 1,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DurationMinutes != 60 || cfg.HotkeyModifiers != 12 {
+					t.Errorf("fields not preserved: %+v", cfg)
+				}
+				checkDefaultProfile(t, cfg)
+			},
+		},
+		{
+			fixture:     "v2.json",
+			fromVersion: 2,
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.Profiles) != 2 || cfg.ActiveProfile != "focus" {
+					t.Errorf("profiles not preserved: %+v", cfg.Profiles)
+				}
+				if _, ok := cfg.Profiles[DefaultProfile]; ok {
+					t.Errorf("default profile added to a file that has none")
+				}
+				if cfg.Profiles["meetings"].AlertStyle != "blink" {
+					t.Errorf("meetings profile = %+v", cfg.Profiles["meetings"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			path, original := copyFixture(t, tt.fixture)
+
+			cfg, err := loadFile(path)
+			if err != nil {
+				t.Fatalf("loadFile: %v", err)
+			}
+			if cfg.Version != CurrentVersion {
+				t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+			}
+			tt.check(t, cfg)
+
+			backups, _ := filepath.Glob(path + ".v*.bak")
+			if tt.fromVersion == CurrentVersion {
+				if len(backups) != 0 {
+					t.Errorf("unexpected backups for current version: %v", backups)
+				}
+				return
+			}
+			if len(backups) != 1 {
+				t.Fatalf("expected one backup, got %v", backups)
+			}
+			backup, err := os.ReadFile(backups[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(backup, original) {
+				t.Errorf("backup differs from the pre-migration file")
+			}
+
+			// The migrated file is written back and loads without migrating again.
+			again, err := loadFile(path)
+			if err != nil {
+				t.Fatalf("reload: %v", err)
+			}
+			if !reflect.DeepEqual(again, cfg) {
+				t.Errorf("reloaded config differs:\n got %+v\nwant %+v", again, cfg)
+			}
+		})
+	}
+}
+
+// checkDefaultProfile verifies that files from before profiles existed get a
+// default profile holding their own settings.
+func checkDefaultProfile(t *testing.T, cfg *Config) {
+	t.Helper()
+	if cfg.ActiveProfile != DefaultProfile {
+		t.Errorf("ActiveProfile = %q, want %q", cfg.ActiveProfile, DefaultProfile)
+	}
+	if p := cfg.Profiles[DefaultProfile]; p != cfg.currentProfile() {
+		t.Errorf("default profile %+v does not match settings %+v", p, cfg.currentProfile())
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "duration_minutes": 30}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFile(path); err == nil {
+		t.Fatal("expected an error for a config from a newer version")
+	}
+}
+
+func TestPeekLeavesFilesAlone(t *testing.T) {
+	path, data := copyFixture(t, "v1.json")
+	cfg, err := peekFile(path)
+	if err != nil {
+		t.Fatalf("peekFile: %v", err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want the migration applied in memory", cfg.Version)
+	}
+
+	bad := filepath.Join(filepath.Dir(path), "bad.json")
+	badData := []byte(`{"version": 1, "alert_style": "banana"}`)
+	if err := os.WriteFile(bad, badData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = peekFile(bad)
+	var lerr *LoadError
+	if !errors.As(err, &lerr) || lerr.Moved != "" {
+		t.Fatalf("peekFile(bad) error = %v, want a LoadError that moved nothing", err)
+	}
+	if cfg.AlertStyle != DefaultConfig().AlertStyle {
+		t.Errorf("AlertStyle = %q, want the fallback", cfg.AlertStyle)
+	}
+
+	if _, err := peekFile(filepath.Join(filepath.Dir(path), "missing.json")); err != nil {
+		t.Errorf("peekFile(missing): %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("peek wrote files: %v", entries)
+	}
+	for name, want := range map[string][]byte{path: data, bad: badData} {
+		if got, _ := os.ReadFile(name); !bytes.Equal(got, want) {
+			t.Errorf("%s changed by peek", filepath.Base(name))
+		}
+	}
+}
+
+func TestConfigFilesArePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no Unix permission bits")
+	}
+	path, _ := copyFixture(t, "v1.json")
+	if _, err := loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	bad := filepath.Join(filepath.Dir(path), "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"version": 2, "alert_style": "banana"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loadFile(bad)
+
+	backups, _ := filepath.Glob(path + ".v*.bak")
+	for _, name := range append(backups, path, bad, bad+".invalid") {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s has mode %v, want 0600", filepath.Base(name), perm)
+		}
+	}
+}
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	if len(migrations) != CurrentVersion {
+		t.Fatalf("%d migrations for CurrentVersion %d", len(migrations), CurrentVersion)
+	}
+}
+
+func TestConcurrentSavesLeaveValidFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(mins int) {
+			defer wg.Done()
+			cfg := DefaultConfig()
+			cfg.DurationMinutes = mins
+			if err := saveFile(path, cfg); err != nil {
+				t.Errorf("saveFile: %v", err)
+			}
+		}(i + 1)
+	}
+	wg.Wait()
+
+	if _, err := loadFile(path); err != nil {
+		t.Fatalf("file unreadable after concurrent saves: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("temp files left behind: %v", names)
+	}
+}
+
+func TestSetDirIsCreatedOnSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "hydra")
+	SetDir(dir)
+	defer SetDir("")
+
+	path, err := GetConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "config.json") {
+		t.Errorf("GetConfigPath() = %q", path)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("GetConfigPath created the directory")
+	}
+
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("config not created by Load: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
+// Live publishes the current config to readers on other goroutines, such as
+// the timer callbacks and the control socket. Its owner changes a config of
+// its own and stores a copy after each change; readers load a snapshot that
+// never changes under them.
+type Live struct {
+	cfg atomic.Pointer[Config]
+}
+
+// NewLive returns a Live holding a copy of cfg.
+func NewLive(cfg *Config) *Live {
+	l := &Live{}
+	l.Store(cfg)
+	return l
+}
+
+// Load returns the current snapshot. Callers must not modify it.
+func (l *Live) Load() *Config {
+	return l.cfg.Load()
+}
+
+// Store publishes a copy of cfg, so the caller may go on changing cfg.
+func (l *Live) Store(cfg *Config) {
+	l.cfg.Store(cfg.Clone())
+}
+
+// Clone returns a copy of c that shares no slices or maps with it.
+func (c *Config) Clone() *Config {
+	out := *c
+	out.AutostartArgs = slices.Clone(c.AutostartArgs)
+	out.Profiles = maps.Clone(c.Profiles)
+	if c.Webhooks != nil {
+		out.Webhooks = make([]Webhook, len(c.Webhooks))
+		for i, w := range c.Webhooks {
+			w.Headers = maps.Clone(w.Headers)
+			w.Events = slices.Clone(w.Events)
+			out.Webhooks[i] = w
+		}
+	}
+	return &out
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestLiveStoresCopy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AutostartArgs = []string{"--profile", "work"}
+	cfg.Webhooks = []Webhook{{URL: "http://example.com", Headers: map[string]string{"A": "1"}, Events: []string{"alert"}}}
+	live := NewLive(cfg)
+
+	cfg.DurationMinutes = 5
+	cfg.AutostartArgs[1] = "home"
+	cfg.Profiles[DefaultProfile] = Profile{DurationMinutes: 5}
+	cfg.Webhooks[0].Headers["A"] = "2"
+	cfg.Webhooks[0].Events[0] = "reset"
+
+	got := live.Load()
+	if got.DurationMinutes == 5 || got.AutostartArgs[1] != "work" {
+		t.Errorf("snapshot changed with the config: %+v", got)
+	}
+	if got.Profiles[DefaultProfile].DurationMinutes == 5 {
+		t.Errorf("snapshot shares profiles with the config")
+	}
+	if w := got.Webhooks[0]; w.Headers["A"] != "1" || w.Events[0] != "alert" {
+		t.Errorf("snapshot shares webhooks with the config: %+v", w)
+	}
+}
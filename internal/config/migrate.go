@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// migrations[v] upgrades a raw config document from version v to v+1. Every
+// change to the on-disk format adds a function here and bumps CurrentVersion,
+// older steps are never edited so each historical shape keeps loading.
+var migrations = []func(doc map[string]any) error{
+	migrateV0,
+	migrateV1,
+}
+
+// migrate upgrades data to CurrentVersion and returns the upgraded document
+// together with the version it started from.
+func migrate(data []byte) ([]byte, int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	from, err := docVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > CurrentVersion {
+		return nil, from, fmt.Errorf("config version %d is newer than supported version %d", from, CurrentVersion)
+	}
+	if from == CurrentVersion {
+		return data, from, nil
+	}
+
+	for v := from; v < CurrentVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, from, fmt.Errorf("migrating config from version %d: %w", v, err)
+		}
+		doc["version"] = v + 1
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, from, err
+	}
+	return migrated, from, nil
+}
+
+// docVersion reads the version field, files written before it existed are version 0.
+func docVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+	v, ok := raw.(float64)
+	if !ok || v < 0 || v != float64(int(v)) {
+		return 0, fmt.Errorf("invalid config version %v", raw)
+	}
+	return int(v), nil
+}
+
+// migrateV0 handles files from before versioning. Their fields are a subset of
+// version 1 with the same names and meaning, missing ones fall back to defaults.
+func migrateV0(doc map[string]any) error {
+	return nil
+}
+
+// migrateV1 introduces profiles. The existing reminder settings become the
+// "default" profile so switching away and back restores them.
+func migrateV1(doc map[string]any) error {
+	if _, ok := doc["profiles"]; ok {
+		return nil
+	}
+	defaults := map[string]any{}
+	def, err := json.Marshal(DefaultConfig().currentProfile())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(def, &defaults); err != nil {
+		return err
+	}
+
+	profile := map[string]any{}
+	for _, field := range profileFields {
+		if v, ok := doc[field]; ok {
+			profile[field] = v
+		} else {
+			profile[field] = defaults[field]
+		}
+	}
+	doc["profiles"] = map[string]any{DefaultProfile: profile}
+	doc["active_profile"] = DefaultProfile
+	return nil
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// setting, e.g. HYDRA_ALERT_STYLE for alert_style.
+const EnvPrefix = "HYDRA_"
+
+// Overrides are session-only settings taken from command-line flags and HYDRA_*
+// environment variables. They are applied on top of the config file and never
+// saved. Precedence is flags > environment > file > defaults.
+type Overrides struct {
+	mu    sync.Mutex
+	env   map[string]string // JSON field name -> raw value
+	flags map[string]string
+
+	// Profile to switch to for this session, applied before the settings above.
+	envProfile  string
+	flagProfile string
+	fileProfile string // the file's active profile while the override is in effect
+
+	// fileValues holds what the file said for each overridden field, so the
+	// file keeps its own values when the app saves.
+	fileValues map[string]reflect.Value
+	applied    map[string]reflect.Value
+}
+
+func NewOverrides() *Overrides {
+	return &Overrides{
+		env:   map[string]string{},
+		flags: map[string]string{},
+	}
+}
+
+// setting is one scalar Config field that can be overridden. Fields of
+// nested settings such as pomodoro are named with a dot, e.g. pomodoro.enabled.
+type setting struct {
+	name  string // JSON field name
+	index []int
+	kind  reflect.Kind
+}
+
+// settings lists the overridable fields. Lists and maps (autostart_args,
+// webhooks, profiles) are only set in the config file.
+func settings() []setting {
+	return structSettings(reflect.TypeOf(Config{}), "", nil)
+}
+
+func structSettings(t reflect.Type, prefix string, index []int) []setting {
+	var out []setting
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		// The active profile is switched with --profile, which changes the
+		// profile's settings along with it.
+		if name == "" || name == "-" || name == "version" || name == "active_profile" {
+			continue
+		}
+		name = prefix + name
+		fieldIndex := append(append([]int(nil), index...), i)
+		switch kind := t.Field(i).Type.Kind(); kind {
+		case reflect.Bool, reflect.Int, reflect.Uint32, reflect.String:
+			out = append(out, setting{name: name, index: fieldIndex, kind: kind})
+		case reflect.Struct:
+			out = append(out, structSettings(t.Field(i).Type, name+".", fieldIndex)...)
+		}
+	}
+	return out
+}
+
+func lookupSetting(name string) (setting, bool) {
+	for _, s := range settings() {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func (s setting) parse(raw string) (reflect.Value, error) {
+	switch s.kind {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		return reflect.ValueOf(b), err
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		return reflect.ValueOf(n), err
+	case reflect.Uint32:
+		// Base 0 accepts hex such as 0x0006 for modifiers.
+		n, err := strconv.ParseUint(raw, 0, 32)
+		return reflect.ValueOf(uint32(n)), err
+	default:
+		return reflect.ValueOf(raw), nil
+	}
+}
+
+// FlagName is the command-line flag for a JSON field name, e.g. alert-style
+// or pomodoro.work-minutes.
+func FlagName(field string) string {
+	return strings.ReplaceAll(field, "_", "-")
+}
+
+// EnvName is the environment variable for a JSON field name, e.g.
+// HYDRA_ALERT_STYLE or HYDRA_POMODORO_WORK_MINUTES.
+func EnvName(field string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+}
+
+func lookupEnv(key string) (setting, bool) {
+	for _, s := range settings() {
+		if EnvName(s.name) == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// overrideFlag records a flag value into Overrides.flags.
+type overrideFlag struct {
+	o       *Overrides
+	setting setting
+}
+
+func (f overrideFlag) String() string { return "" }
+
+func (f overrideFlag) Set(raw string) error {
+	if _, err := f.setting.parse(raw); err != nil {
+		return err
+	}
+	f.o.flags[f.setting.name] = raw
+	return nil
+}
+
+func (f overrideFlag) IsBoolFlag() bool { return f.setting.kind == reflect.Bool }
+
+// durationFlag is --duration, a friendlier form of --duration-minutes.
+type durationFlag struct{ o *Overrides }
+
+func (f durationFlag) String() string { return "" }
+
+func (f durationFlag) Set(raw string) error {
+	mins, err := parseDurationMinutes(raw)
+	if err != nil {
+		return err
+	}
+	f.o.flags["duration_minutes"] = strconv.Itoa(mins)
+	return nil
+}
+
+// noHotkeyFlag is --no-hotkey.
+type noHotkeyFlag struct{ o *Overrides }
+
+func (f noHotkeyFlag) String() string   { return "" }
+func (f noHotkeyFlag) IsBoolFlag() bool { return true }
+
+func (f noHotkeyFlag) Set(raw string) error {
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return err
+	}
+	f.o.flags["hotkey_enabled"] = strconv.FormatBool(!b)
+	return nil
+}
+
+// parseDurationMinutes turns "25m" or "1h30m" into whole minutes.
+func parseDurationMinutes(raw string) (int, error) {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 || d%time.Minute != 0 {
+		return 0, fmt.Errorf("duration %q must be a positive whole number of minutes", raw)
+	}
+	return int(d / time.Minute), nil
+}
+
+// profileFlag is --profile.
+type profileFlag struct{ o *Overrides }
+
+func (f profileFlag) String() string { return "" }
+
+func (f profileFlag) Set(raw string) error {
+	f.o.flagProfile = raw
+	return nil
+}
+
+// RegisterFlags adds a flag for every setting to fs, named after its JSON field
+// (--alert-style, --pomodoro.enabled, ...), plus --duration 25m, --no-hotkey and
+// --profile.
+func (o *Overrides) RegisterFlags(fs *flag.FlagSet) {
+	for _, s := range settings() {
+		fs.Var(overrideFlag{o: o, setting: s}, FlagName(s.name),
+			fmt.Sprintf("override %s for this session (env %s)", s.name, EnvName(s.name)))
+	}
+	fs.Var(durationFlag{o: o}, "duration", "reminder interval for this session, e.g. 25m (env HYDRA_DURATION)")
+	fs.Var(noHotkeyFlag{o: o}, "no-hotkey", "disable the global hotkey for this session")
+	fs.Var(profileFlag{o: o}, "profile", "use this profile for the session (env HYDRA_PROFILE)")
+}
+
+// LoadEnv reads HYDRA_* variables from environ (as returned by os.Environ).
+// Unknown variables are ignored, invalid values are reported and skipped.
+func (o *Overrides) LoadEnv(environ []string) error {
+	var errs []string
+	var duration string
+	for _, kv := range environ {
+		key, raw, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		if key == EnvPrefix+"PROFILE" {
+			o.envProfile = raw
+			continue
+		}
+		if key == EnvPrefix+"DURATION" {
+			mins, err := parseDurationMinutes(raw)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+				continue
+			}
+			duration = strconv.Itoa(mins)
+			continue
+		}
+		s, ok := lookupEnv(key)
+		if !ok {
+			continue
+		}
+		if _, err := s.parse(raw); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		o.env[s.name] = raw
+	}
+	// HYDRA_DURATION_MINUTES is more specific and wins over HYDRA_DURATION.
+	if _, ok := o.env["duration_minutes"]; !ok && duration != "" {
+		o.env["duration_minutes"] = duration
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("ignoring invalid environment overrides: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Empty reports whether there is nothing to override.
+func (o *Overrides) Empty() bool {
+	return o == nil || (len(o.env) == 0 && len(o.flags) == 0 && o.profile() == "")
+}
+
+func (o *Overrides) profile() string {
+	if o.flagProfile != "" {
+		return o.flagProfile
+	}
+	return o.envProfile
+}
+
+// Apply puts the overrides on top of cfg, which holds the file's values. It
+// returns an error when an overridden value is invalid.
+func (o *Overrides) Apply(cfg *Config) error {
+	if o.Empty() {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	merged := map[string]string{}
+	for k, v := range o.env {
+		merged[k] = v
+	}
+	for k, v := range o.flags {
+		merged[k] = v
+	}
+
+	o.fileValues = map[string]reflect.Value{}
+	o.applied = map[string]reflect.Value{}
+	o.fileProfile = cfg.ActiveProfile
+	rv := reflect.ValueOf(cfg).Elem()
+
+	if name := o.profile(); name != "" {
+		before := reflect.ValueOf(*cfg)
+		if err := cfg.SwitchProfile(name); err != nil {
+			return err
+		}
+		// Every setting the profile changed counts as overridden.
+		for _, s := range settings() {
+			if before.FieldByIndex(s.index).Interface() != rv.FieldByIndex(s.index).Interface() {
+				o.fileValues[s.name] = before.FieldByIndex(s.index)
+				o.applied[s.name] = reflect.ValueOf(rv.FieldByIndex(s.index).Interface())
+			}
+		}
+	}
+
+	for name, raw := range merged {
+		s, _ := lookupSetting(name)
+		v, err := s.parse(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		field := rv.FieldByIndex(s.index)
+		if _, ok := o.fileValues[name]; !ok {
+			o.fileValues[name] = reflect.ValueOf(field.Interface())
+		}
+		o.applied[name] = v
+		field.Set(v)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid override: %w", err)
+	}
+	return nil
+}
+
+// ForSave returns a copy of cfg with the file's values in place of overridden
+// ones, so overrides stay session-only. A setting the user changed during the
+// session, e.g. from the tray menu, is no longer treated as overridden and is
+// saved as changed.
+func (o *Overrides) ForSave(cfg *Config) *Config {
+	if o == nil {
+		return cfg
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.applied) == 0 && o.profile() == "" {
+		return cfg
+	}
+
+	out := *cfg
+	if name := o.profile(); name != "" {
+		if out.ActiveProfile == name {
+			out.ActiveProfile = o.fileProfile
+		} else {
+			// The user switched profiles during the session.
+			o.flagProfile, o.envProfile = "", ""
+		}
+	}
+	rv := reflect.ValueOf(&out).Elem()
+	for name, applied := range o.applied {
+		s, _ := lookupSetting(name)
+		field := rv.FieldByIndex(s.index)
+		if field.Interface() != applied.Interface() {
+			delete(o.applied, name)
+			delete(o.env, name)
+			delete(o.flags, name)
+			continue
+		}
+		field.Set(o.fileValues[name])
+	}
+	return &out
+}
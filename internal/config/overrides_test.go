@@ -0,0 +1,140 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func parseOverrides(t *testing.T, args, environ []string) *Overrides {
+	t.Helper()
+	o := NewOverrides()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	o.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("parse %v: %v", args, err)
+	}
+	if err := o.LoadEnv(environ); err != nil {
+		t.Fatalf("LoadEnv: %v", err)
+	}
+	return o
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	o := parseOverrides(t,
+		[]string{"--duration", "25m", "--no-hotkey"},
+		[]string{"HYDRA_DURATION=50m", "HYDRA_ALERT_STYLE=blink", "HYDRA_LOG_LEVEL=debug", "PATH=/bin"},
+	)
+
+	cfg := DefaultConfig()
+	cfg.HotkeyEnabled = true
+	cfg.SnoozeMinutes = 7 // from the file, not overridden
+	if err := o.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if cfg.DurationMinutes != 25 {
+		t.Errorf("DurationMinutes = %d, want 25 (flag beats env)", cfg.DurationMinutes)
+	}
+	if cfg.AlertStyle != "blink" || cfg.LogLevel != "debug" {
+		t.Errorf("env overrides not applied: %+v", cfg)
+	}
+	if cfg.HotkeyEnabled {
+		t.Errorf("--no-hotkey not applied")
+	}
+	if cfg.SnoozeMinutes != 7 {
+		t.Errorf("file value lost: SnoozeMinutes = %d", cfg.SnoozeMinutes)
+	}
+}
+
+func TestOverridesAreNotSaved(t *testing.T) {
+	o := parseOverrides(t, []string{"--alert-style", "blink", "--duration-minutes", "45"}, nil)
+
+	cfg := DefaultConfig()
+	if err := o.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	// The user picks a new duration from the menu, that one should stick.
+	cfg.DurationMinutes = 15
+
+	saved := o.ForSave(cfg)
+	if saved.AlertStyle != DefaultConfig().AlertStyle {
+		t.Errorf("override persisted: AlertStyle = %q", saved.AlertStyle)
+	}
+	if saved.DurationMinutes != 15 {
+		t.Errorf("session change lost: DurationMinutes = %d, want 15", saved.DurationMinutes)
+	}
+	if cfg.AlertStyle != "blink" {
+		t.Errorf("ForSave modified the live config")
+	}
+}
+
+func TestNestedOverrides(t *testing.T) {
+	o := parseOverrides(t,
+		[]string{"--pomodoro.enabled", "--pomodoro.work-minutes", "50"},
+		[]string{"HYDRA_MQTT_BROKER=tcp://broker:1883", "HYDRA_POMODORO_WORK_MINUTES=20"},
+	)
+	cfg := DefaultConfig()
+	if err := o.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if !cfg.Pomodoro.Enabled || cfg.Pomodoro.WorkMinutes != 50 {
+		t.Errorf("Pomodoro = %+v, want enabled with 50 min work (flag beats env)", cfg.Pomodoro)
+	}
+	if cfg.MQTT.Broker != "tcp://broker:1883" {
+		t.Errorf("MQTT.Broker = %q, want the env value", cfg.MQTT.Broker)
+	}
+
+	saved := o.ForSave(cfg)
+	def := DefaultConfig()
+	if saved.Pomodoro != def.Pomodoro || saved.MQTT.Broker != def.MQTT.Broker {
+		t.Errorf("nested overrides persisted: %+v %+v", saved.Pomodoro, saved.MQTT)
+	}
+	if !cfg.Pomodoro.Enabled {
+		t.Errorf("ForSave modified the live config")
+	}
+}
+
+func TestInvalidOverrides(t *testing.T) {
+	o := NewOverrides()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	o.RegisterFlags(fs)
+	if err := fs.Parse([]string{"--duration", "90s"}); err == nil {
+		t.Error("expected --duration 90s to be rejected")
+	}
+
+	if err := o.LoadEnv([]string{"HYDRA_SNOOZE_MINUTES=soon"}); err == nil {
+		t.Error("expected invalid HYDRA_SNOOZE_MINUTES to be reported")
+	}
+
+	o = parseOverrides(t, []string{"--alert-style", "banana"}, nil)
+	if err := o.Apply(DefaultConfig()); err == nil {
+		t.Error("expected --alert-style banana to fail validation")
+	}
+}
+
+func TestProfileOverride(t *testing.T) {
+	o := parseOverrides(t, []string{"--profile", "focus", "--snooze-minutes", "2"}, nil)
+
+	cfg := DefaultConfig()
+	cfg.Profiles["focus"] = Profile{DurationMinutes: 50, AlertColor: "#FF0000", AlertStyle: "blink", SnoozeMinutes: 10}
+	if err := o.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if cfg.ActiveProfile != "focus" || cfg.DurationMinutes != 50 || cfg.AlertStyle != "blink" {
+		t.Errorf("profile not applied: %+v", cfg)
+	}
+	if cfg.SnoozeMinutes != 2 {
+		t.Errorf("SnoozeMinutes = %d, explicit flag should beat the profile", cfg.SnoozeMinutes)
+	}
+
+	saved := o.ForSave(cfg)
+	def := DefaultConfig()
+	if saved.ActiveProfile != DefaultProfile || saved.DurationMinutes != def.DurationMinutes ||
+		saved.AlertStyle != def.AlertStyle || saved.SnoozeMinutes != def.SnoozeMinutes {
+		t.Errorf("session profile persisted: %+v", saved)
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultProfile is created for new configs and for configs from before profiles existed.
+const DefaultProfile = "default"
+
+// Profile is a named set of reminder settings, see Config.Profiles.
+type Profile struct {
+	DurationMinutes int    `json:"duration_minutes"`
+	AlertColor      string `json:"alert_color"`
+	AlertStyle      string `json:"alert_style"`
+	SnoozeMinutes   int    `json:"snooze_minutes"`
+}
+
+// profileFields are the JSON names of the top-level fields a profile carries.
+var profileFields = []string{"duration_minutes", "alert_color", "alert_style", "snooze_minutes"}
+
+func (c *Config) currentProfile() Profile {
+	return Profile{
+		DurationMinutes: c.DurationMinutes,
+		AlertColor:      c.AlertColor,
+		AlertStyle:      c.AlertStyle,
+		SnoozeMinutes:   c.SnoozeMinutes,
+	}
+}
+
+func (c *Config) applyProfile(p Profile) {
+	c.DurationMinutes = p.DurationMinutes
+	c.AlertColor = p.AlertColor
+	c.AlertStyle = p.AlertStyle
+	c.SnoozeMinutes = p.SnoozeMinutes
+}
+
+// ProfileNames returns the profile names in alphabetical order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchProfile makes name the active profile and copies its settings over the
+// top-level ones. Changes made to the previously active profile are kept.
+func (c *Config) SwitchProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	*c = *c.withActiveProfileSynced()
+	c.applyProfile(p)
+	c.ActiveProfile = name
+	return nil
+}
+
+// SaveProfile stores the current top-level settings as profile name and makes
+// it the active one.
+func (c *Config) SaveProfile(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	profiles := make(map[string]Profile, len(c.Profiles)+1)
+	for k, v := range c.Profiles {
+		profiles[k] = v
+	}
+	profiles[name] = c.currentProfile()
+	c.Profiles = profiles
+	c.ActiveProfile = name
+	return nil
+}
+
+// withActiveProfileSynced returns c, or a copy of it whose active profile
+// holds the current top-level settings. The profile map is copied rather than
+// modified since it may be shared with the live config.
+func (c *Config) withActiveProfileSynced() *Config {
+	p, ok := c.Profiles[c.ActiveProfile]
+	if !ok || p == c.currentProfile() {
+		return c
+	}
+	out := *c
+	out.Profiles = make(map[string]Profile, len(c.Profiles))
+	for k, v := range c.Profiles {
+		out.Profiles[k] = v
+	}
+	out.Profiles[c.ActiveProfile] = c.currentProfile()
+	return &out
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSwitchProfileKeepsChangesToPreviousProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DurationMinutes = 50
+	if err := cfg.SaveProfile("focus"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SwitchProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DurationMinutes != DefaultConfig().DurationMinutes {
+		t.Errorf("DurationMinutes = %d after switching to default", cfg.DurationMinutes)
+	}
+
+	// A change made while "default" is active belongs to "default".
+	cfg.AlertStyle = "blink"
+	if err := cfg.SwitchProfile("focus"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DurationMinutes != 50 || cfg.AlertStyle != "color" {
+		t.Errorf("focus settings not applied: %+v", cfg.currentProfile())
+	}
+	if cfg.Profiles[DefaultProfile].AlertStyle != "blink" {
+		t.Errorf("change to default profile lost: %+v", cfg.Profiles[DefaultProfile])
+	}
+
+	if err := cfg.SwitchProfile("missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestSaveSyncsActiveProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := DefaultConfig()
+	cfg.DurationMinutes = 45
+	if err := saveFile(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Profiles[DefaultProfile].DurationMinutes != 45 {
+		t.Errorf("active profile not synced on save: %+v", loaded.Profiles[DefaultProfile])
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package config
+
+import "os"
+
+// syncDir flushes a rename in dir to disk. Failures only weaken durability,
+// the rename itself already happened, so they are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
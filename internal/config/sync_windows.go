@@ -0,0 +1,7 @@
+//go:build windows
+
+package config
+
+// syncDir is a no-op, Windows cannot open directories for syncing and
+// MoveFileEx already replaces the file in a single step.
+func syncDir(dir string) {}
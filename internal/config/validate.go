@@ -0,0 +1,495 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// FieldError describes one invalid config value.
+type FieldError struct {
+	Field string // JSON name of the field
+	Value any
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s (got %v)", e.Field, e.Msg, e.Value)
+}
+
+// ValidationError lists every invalid field of a config.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// LoadError is returned by Load when the config file could not be used as is.
+// Load still returns a usable config in that case: defaults for a file that
+// did not parse, or the file's values with invalid fields set to defaults.
+type LoadError struct {
+	Err   error  // the parse error or a *ValidationError
+	Moved string // where the original file was kept, empty if it could not be moved
+}
+
+func (e *LoadError) Error() string {
+	if e.Moved == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (original kept as %s)", e.Err, e.Moved)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Problems returns one line per problem, suitable for showing to the user.
+func (e *LoadError) Problems() []string {
+	if verr, ok := e.Err.(*ValidationError); ok {
+		msgs := make([]string, len(verr.Fields))
+		for i, f := range verr.Fields {
+			msgs[i] = f.Error()
+		}
+		return msgs
+	}
+	return []string{e.Err.Error()}
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// Windows MOD_ALT | MOD_CONTROL | MOD_SHIFT | MOD_WIN, the only modifiers both
+// hotkey backends understand.
+const supportedModifiers = 0x000F
+
+// fieldRule checks one field and knows how to put its default back.
+type fieldRule struct {
+	field string
+	value func(c *Config) any
+	check func(c *Config) string // returns "" when valid
+	reset func(c, def *Config)
+}
+
+var rules = []fieldRule{
+	{
+		field: "duration_minutes",
+		value: func(c *Config) any { return c.DurationMinutes },
+		check: func(c *Config) string {
+			// 0 is the 10 second debug duration.
+			if c.DurationMinutes < 0 || c.DurationMinutes > 24*60 {
+				return "must be between 0 and 1440 minutes"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.DurationMinutes = def.DurationMinutes },
+	},
+	{
+		field: "alert_color",
+		value: func(c *Config) any { return c.AlertColor },
+		check: func(c *Config) string {
+			if !colorPattern.MatchString(c.AlertColor) {
+				return "must be a color like #FF0000"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.AlertColor = def.AlertColor },
+	},
+	{
+		field: "alert_style",
+		value: func(c *Config) any { return c.AlertStyle },
+		check: func(c *Config) string {
+			if c.AlertStyle != "color" && c.AlertStyle != "blink" {
+				return `must be "color" or "blink"`
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.AlertStyle = def.AlertStyle },
+	},
+	{
+		field: "hotkey_modifiers",
+		value: func(c *Config) any { return fmt.Sprintf("0x%04X", c.HotkeyModifiers) },
+		check: func(c *Config) string {
+			if c.HotkeyModifiers == 0 {
+				return "at least one modifier is required"
+			}
+			if c.HotkeyModifiers&^supportedModifiers != 0 {
+				return "only ALT (0x1), CTRL (0x2), SHIFT (0x4) and WIN (0x8) are supported"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.HotkeyModifiers = def.HotkeyModifiers },
+	},
+	{
+		field: "hotkey_reset_key",
+		value: func(c *Config) any { return c.HotkeyResetKey },
+		check: func(c *Config) string { return checkKey(c.HotkeyResetKey) },
+		reset: func(c, def *Config) { c.HotkeyResetKey = def.HotkeyResetKey },
+	},
+	{
+		field: "chord_leader_key",
+		value: func(c *Config) any { return c.ChordLeaderKey },
+		check: func(c *Config) string { return checkKey(c.ChordLeaderKey) },
+		reset: func(c, def *Config) { c.ChordLeaderKey = def.ChordLeaderKey },
+	},
+	{
+		field: "chord_timeout_ms",
+		value: func(c *Config) any { return c.ChordTimeoutMs },
+		check: func(c *Config) string {
+			if c.ChordTimeoutMs < 200 || c.ChordTimeoutMs > 10000 {
+				return "must be between 200 and 10000 milliseconds"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.ChordTimeoutMs = def.ChordTimeoutMs },
+	},
+	{
+		field: "snooze_minutes",
+		value: func(c *Config) any { return c.SnoozeMinutes },
+		check: func(c *Config) string {
+			if c.SnoozeMinutes < 1 || c.SnoozeMinutes > 120 {
+				return "must be between 1 and 120 minutes"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.SnoozeMinutes = def.SnoozeMinutes },
+	},
+	{
+		field: "autostart_backend",
+		value: func(c *Config) any { return c.AutostartBackend },
+		check: func(c *Config) string {
+			switch c.AutostartBackend {
+			case "", "xdg", "systemd", "registry":
+				return ""
+			}
+			return `must be empty, "xdg", "systemd" or "registry"`
+		},
+		reset: func(c, def *Config) { c.AutostartBackend = def.AutostartBackend },
+	},
+	{
+		field: "autostart_delay_seconds",
+		value: func(c *Config) any { return c.AutostartDelaySeconds },
+		check: func(c *Config) string {
+			if c.AutostartDelaySeconds < 0 || c.AutostartDelaySeconds > 600 {
+				return "must be between 0 and 600 seconds"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.AutostartDelaySeconds = def.AutostartDelaySeconds },
+	},
+	{
+		field: "tray_left_click",
+		value: func(c *Config) any { return c.TrayLeftClick },
+		check: func(c *Config) string { return checkTrayAction(c.TrayLeftClick) },
+		reset: func(c, def *Config) { c.TrayLeftClick = def.TrayLeftClick },
+	},
+	{
+		field: "tray_middle_click",
+		value: func(c *Config) any { return c.TrayMiddleClick },
+		check: func(c *Config) string { return checkTrayAction(c.TrayMiddleClick) },
+		reset: func(c, def *Config) { c.TrayMiddleClick = def.TrayMiddleClick },
+	},
+	{
+		field: "tray_scroll_up",
+		value: func(c *Config) any { return c.TrayScrollUp },
+		check: func(c *Config) string { return checkTrayAction(c.TrayScrollUp) },
+		reset: func(c, def *Config) { c.TrayScrollUp = def.TrayScrollUp },
+	},
+	{
+		field: "tray_scroll_down",
+		value: func(c *Config) any { return c.TrayScrollDown },
+		check: func(c *Config) string { return checkTrayAction(c.TrayScrollDown) },
+		reset: func(c, def *Config) { c.TrayScrollDown = def.TrayScrollDown },
+	},
+	{
+		field: "tray_menu_open",
+		value: func(c *Config) any { return c.TrayMenuOpen },
+		check: func(c *Config) string { return checkTrayAction(c.TrayMenuOpen) },
+		reset: func(c, def *Config) { c.TrayMenuOpen = def.TrayMenuOpen },
+	},
+	{
+		field: "scroll_step_minutes",
+		value: func(c *Config) any { return c.ScrollStepMinutes },
+		check: func(c *Config) string {
+			if c.ScrollStepMinutes < 1 || c.ScrollStepMinutes > 60 {
+				return "must be between 1 and 60 minutes"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.ScrollStepMinutes = def.ScrollStepMinutes },
+	},
+	{
+		field: "http_port",
+		value: func(c *Config) any { return c.HTTPPort },
+		check: func(c *Config) string {
+			if c.HTTPPort < 1024 || c.HTTPPort > 65535 {
+				return "must be between 1024 and 65535"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.HTTPPort = def.HTTPPort },
+	},
+	{
+		field: "pomodoro",
+		value: func(c *Config) any { return c.Pomodoro },
+		check: func(c *Config) string {
+			p := c.Pomodoro
+			for _, m := range []int{p.WorkMinutes, p.ShortBreakMinutes, p.LongBreakMinutes} {
+				if m < 1 || m > 180 {
+					return "phases must be between 1 and 180 minutes"
+				}
+			}
+			if p.CyclesBeforeLongBreak < 1 || p.CyclesBeforeLongBreak > 12 {
+				return "cycles_before_long_break must be between 1 and 12"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.Pomodoro = def.Pomodoro },
+	},
+	{
+		field: "breaks",
+		value: func(c *Config) any { return c.Breaks },
+		check: func(c *Config) string {
+			if c.Breaks.MinimumMinutes < 1 || c.Breaks.MinimumMinutes > 60 {
+				return "minimum_minutes must be between 1 and 60"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.Breaks = def.Breaks },
+	},
+	{
+		field: "adaptive",
+		value: func(c *Config) any { return c.Adaptive },
+		check: func(c *Config) string {
+			a := c.Adaptive
+			if a.MinMinutes < 1 || a.MaxMinutes > 24*60 || a.MinMinutes > a.MaxMinutes {
+				return "min_minutes and max_minutes must be between 1 and 1440, min_minutes first"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.Adaptive = def.Adaptive },
+	},
+	{
+		field: "alert_limit",
+		value: func(c *Config) any { return c.AlertLimit },
+		check: func(c *Config) string {
+			l := c.AlertLimit
+			if l.MaxPerDay < 0 || l.MaxPerDay > 1000 {
+				return "max_per_day must be between 0 and 1000"
+			}
+			if l.MinGapMinutes < 0 || l.MinGapMinutes > 24*60 {
+				return "min_gap_minutes must be between 0 and 1440"
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.AlertLimit = def.AlertLimit },
+	},
+	{
+		field: "webhooks",
+		value: func(c *Config) any { return len(c.Webhooks) },
+		check: func(c *Config) string {
+			for i, w := range c.Webhooks {
+				if msg := checkWebhook(w); msg != "" {
+					return fmt.Sprintf("webhook %d: %s", i+1, msg)
+				}
+			}
+			return ""
+		},
+		reset: func(c, def *Config) { c.Webhooks = def.Webhooks },
+	},
+	{
+		field: "mqtt",
+		value: func(c *Config) any { return c.MQTT.Broker },
+		check: func(c *Config) string { return checkMQTT(c.MQTT) },
+		reset: func(c, def *Config) { c.MQTT = def.MQTT },
+	},
+	{
+		field: "log_level",
+		value: func(c *Config) any { return c.LogLevel },
+		check: func(c *Config) string {
+			switch c.LogLevel {
+			case "debug", "info", "warn", "error":
+				return ""
+			}
+			return `must be "debug", "info", "warn" or "error"`
+		},
+		reset: func(c, def *Config) { c.LogLevel = def.LogLevel },
+	},
+}
+
+// checkKey accepts the virtual key codes of A-Z and 0-9, the keys both hotkey
+// backends can map.
+func checkKey(vk uint32) string {
+	if (vk >= 'A' && vk <= 'Z') || (vk >= '0' && vk <= '9') {
+		return ""
+	}
+	return "must be the virtual key code of A-Z or 0-9"
+}
+
+// TrayActions are what clicking or scrolling on the tray icon can do.
+// "acknowledge" resets only while the alert or a break is showing, "add_time"
+// and "subtract_time" change the time left by ScrollStepMinutes.
+var TrayActions = []string{"none", "acknowledge", "reset", "pause", "snooze", "drink", "add_time", "subtract_time"}
+
+func checkTrayAction(action string) string {
+	if slices.Contains(TrayActions, action) {
+		return ""
+	}
+	return "must be one of " + strings.Join(TrayActions, ", ")
+}
+
+func checkWebhook(w Webhook) string {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an http or https URL"
+	}
+	switch w.Method {
+	case "", "GET", "POST", "PUT", "PATCH":
+	default:
+		return `method must be "GET", "POST", "PUT" or "PATCH"`
+	}
+	for _, ev := range w.Events {
+		if !slices.Contains(WebhookEvents, ev) {
+			return fmt.Sprintf("unknown event %q, must be one of %s", ev, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	if w.Template != "" {
+		// Only the names matter for parsing, internal/webhook has the real funcs.
+		funcs := template.FuncMap{"json": func(any) (string, error) { return "", nil }}
+		if _, err := template.New("webhook").Funcs(funcs).Parse(w.Template); err != nil {
+			return "template: " + err.Error()
+		}
+	}
+	return ""
+}
+
+func checkMQTT(m MQTT) string {
+	if m.Broker == "" {
+		return ""
+	}
+	u, err := url.Parse(m.Broker)
+	if err != nil || u.Host == "" {
+		return "broker must be a URL like tcp://host:1883"
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return "broker scheme must be tcp, ssl, ws or wss"
+	}
+	for _, t := range []struct{ name, topic string }{
+		{"state_topic", m.StateTopic},
+		{"command_topic", m.CommandTopic},
+	} {
+		if t.topic == "" || strings.ContainsAny(t.topic, "+#") {
+			return t.name + " must be a topic without wildcards"
+		}
+	}
+	if m.StateTopic == m.CommandTopic {
+		return "state_topic and command_topic must differ"
+	}
+	if strings.ContainsAny(m.DiscoveryPrefix, "+#") {
+		return "discovery_prefix must not contain wildcards"
+	}
+	return ""
+}
+
+// Validate reports every invalid field. It returns nil or a *ValidationError.
+func (c *Config) Validate() error {
+	var errs []FieldError
+	for _, r := range rules {
+		if msg := r.check(c); msg != "" {
+			errs = append(errs, FieldError{Field: r.field, Value: r.value(c), Msg: msg})
+		}
+	}
+
+	// Profiles are checked with the same rules as the top-level fields they
+	// replace, reported as profiles.<name>.<field>. The active profile is
+	// skipped, saving overwrites it with the top-level fields checked above.
+	for _, name := range c.ProfileNames() {
+		if name == c.ActiveProfile {
+			continue
+		}
+		tmp := *c
+		tmp.applyProfile(c.Profiles[name])
+		for _, r := range rules {
+			if !isProfileField(r.field) {
+				continue
+			}
+			if msg := r.check(&tmp); msg != "" {
+				errs = append(errs, FieldError{Field: "profiles." + name + "." + r.field, Value: r.value(&tmp), Msg: msg})
+			}
+		}
+	}
+	if c.ActiveProfile != "" {
+		if _, ok := c.Profiles[c.ActiveProfile]; !ok {
+			errs = append(errs, FieldError{Field: "active_profile", Value: c.ActiveProfile, Msg: "no profile with this name"})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: errs}
+}
+
+func isProfileField(field string) bool {
+	for _, f := range profileFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFallbacks replaces the fields listed in err with their defaults.
+func (c *Config) applyFallbacks(err *ValidationError) {
+	def := DefaultConfig()
+	for _, f := range err.Fields {
+		if f.Field == "active_profile" {
+			c.ActiveProfile = ""
+			continue
+		}
+		if rest, ok := strings.CutPrefix(f.Field, "profiles."); ok {
+			c.profileFallback(rest, def)
+			continue
+		}
+		for _, r := range rules {
+			if r.field == f.Field {
+				r.reset(c, def)
+			}
+		}
+	}
+}
+
+// profileFallback resets one profile field, given as <name>.<field>, to its default.
+func (c *Config) profileFallback(nameField string, def *Config) {
+	i := strings.LastIndex(nameField, ".")
+	if i < 0 {
+		return
+	}
+	name, field := nameField[:i], nameField[i+1:]
+	p, ok := c.Profiles[name]
+	if !ok {
+		return
+	}
+	tmp := *c
+	tmp.applyProfile(p)
+	for _, r := range rules {
+		if r.field == field {
+			r.reset(&tmp, def)
+		}
+	}
+	profiles := make(map[string]Profile, len(c.Profiles))
+	for k, v := range c.Profiles {
+		profiles[k] = v
+	}
+	profiles[name] = tmp.currentProfile()
+	c.Profiles = profiles
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DurationMinutes = -5
+	cfg.AlertStyle = "banana"
+	cfg.HotkeyModifiers = 0x0102
+	cfg.TrayScrollUp = "explode"
+
+	err := cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v, want *ValidationError", err)
+	}
+	got := map[string]bool{}
+	for _, f := range verr.Fields {
+		got[f.Field] = true
+	}
+	for _, field := range []string{"duration_minutes", "alert_style", "hotkey_modifiers", "tray_scroll_up"} {
+		if !got[field] {
+			t.Errorf("no error reported for %s", field)
+		}
+	}
+	if len(verr.Fields) != 4 {
+		t.Errorf("got %d field errors, want 4: %v", len(verr.Fields), verr)
+	}
+}
+
+func TestValidateMQTT(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*MQTT)
+		valid  bool
+	}{
+		{"off", func(m *MQTT) {}, true},
+		{"tcp", func(m *MQTT) { m.Broker = "tcp://homeassistant.local:1883" }, true},
+		{"tls", func(m *MQTT) { m.Broker = "ssl://broker.example:8883" }, true},
+		{"no host", func(m *MQTT) { m.Broker = "homeassistant.local" }, false},
+		{"http", func(m *MQTT) { m.Broker = "http://broker.example" }, false},
+		{"wildcard", func(m *MQTT) { m.Broker = "tcp://b:1883"; m.CommandTopic = "hydra/#" }, false},
+		{"same topics", func(m *MQTT) { m.Broker = "tcp://b:1883"; m.CommandTopic = m.StateTopic }, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		tt.modify(&cfg.MQTT)
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateWebhookTemplate(t *testing.T) {
+	for tmpl, valid := range map[string]bool{
+		``:                                 true,
+		`{"text": {{json .Event}}}`:        true,
+		`{"text": "{{.Event}}"`:            true, // checked as JSON once rendered
+		`{"text": "{{.Event"}`:             false,
+		`{"text": "{{if .Acknowledged}}"}`: false,
+		`{"text": {{upper .Event}}}`:       false,
+	} {
+		cfg := DefaultConfig()
+		cfg.Webhooks = []Webhook{{URL: "https://example.com/hook", Template: tmpl}}
+		if err := cfg.Validate(); (err == nil) != valid {
+			t.Errorf("template %q: Validate() = %v, want valid %v", tmpl, err, valid)
+		}
+	}
+}
+
+func TestLoadInvalidValuesFallBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := []byte(`{"version": 2, "duration_minutes": 45, "alert_style": "banana", "snooze_minutes": -1}`)
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadFile(path)
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("loadFile error = %v, want *LoadError", err)
+	}
+	if cfg == nil {
+		t.Fatal("expected a usable config")
+	}
+	if cfg.DurationMinutes != 45 {
+		t.Errorf("valid field lost: DurationMinutes = %d", cfg.DurationMinutes)
+	}
+	def := DefaultConfig()
+	if cfg.AlertStyle != def.AlertStyle || cfg.SnoozeMinutes != def.SnoozeMinutes {
+		t.Errorf("invalid fields not reset: %+v", cfg)
+	}
+	if len(loadErr.Problems()) != 2 {
+		t.Errorf("Problems() = %v, want 2 entries", loadErr.Problems())
+	}
+
+	kept, err := os.ReadFile(path + ".invalid")
+	if err != nil {
+		t.Fatalf("original not kept: %v", err)
+	}
+	if string(kept) != string(original) {
+		t.Errorf("kept file was modified")
+	}
+}
+
+func TestLoadSyntaxErrorKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := []byte(`{"duration_minutes": 45,`)
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadFile(path)
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("loadFile error = %v, want *LoadError", err)
+	}
+	if cfg == nil || cfg.DurationMinutes != DefaultConfig().DurationMinutes {
+		t.Errorf("expected defaults, got %+v", cfg)
+	}
+	if loadErr.Moved != path+".invalid" {
+		t.Errorf("Moved = %q", loadErr.Moved)
+	}
+	if kept, _ := os.ReadFile(loadErr.Moved); string(kept) != string(original) {
+		t.Errorf("kept file differs from original")
+	}
+
+	// A second broken edit does not replace the first copy.
+	second := []byte(`{"duration_minutes": 50,`)
+	if err := os.WriteFile(path, second, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFile(path); !errors.As(err, &loadErr) {
+		t.Fatalf("loadFile error = %v, want *LoadError", err)
+	}
+	if loadErr.Moved != path+".invalid.2" {
+		t.Errorf("Moved = %q for the second invalid file", loadErr.Moved)
+	}
+	if kept, _ := os.ReadFile(path + ".invalid"); string(kept) != string(original) {
+		t.Errorf("first kept file was overwritten")
+	}
+
+	// The fallback written in its place loads cleanly.
+	if _, err := loadFile(path); err != nil {
+		t.Errorf("reloading fallback: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	writesMu sync.Mutex
+	// lastWrite holds a hash of what this process last wrote to each path, so
+	// watchers can tell the app's own saves from edits made by someone else.
+	lastWrite = map[string][32]byte{}
+)
+
+func rememberWrite(path string, data []byte) {
+	writesMu.Lock()
+	defer writesMu.Unlock()
+	lastWrite[filepath.Clean(path)] = sha256.Sum256(data)
+}
+
+func isOwnWrite(path string, sum [32]byte) bool {
+	writesMu.Lock()
+	defer writesMu.Unlock()
+	return lastWrite[filepath.Clean(path)] == sum
+}
+
+// pollInterval is how often the polling watcher checks the file.
+const pollInterval = 2 * time.Second
+
+// settleDelay lets editors finish writing before the file is read.
+const settleDelay = 200 * time.Millisecond
+
+// Watch calls onChange whenever the config file at path is changed by someone
+// other than this process. Files that do not parse or validate are reported as
+// an error and the previous settings should be kept. Watch uses inotify where
+// available and falls back to polling. Call stop to end watching.
+func Watch(path string, onChange func(cfg *Config, err error)) (stop func()) {
+	w := &watcher{path: path, onChange: onChange, done: make(chan struct{})}
+	if data, err := os.ReadFile(path); err == nil {
+		w.lastSeen = sha256.Sum256(data)
+	}
+
+	if !w.watchNative() {
+		go w.poll()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(w.done) })
+	}
+}
+
+type watcher struct {
+	path     string
+	onChange func(*Config, error)
+	done     chan struct{}
+	lastSeen [32]byte
+}
+
+// check reads the file and reports it if its content changed.
+func (w *watcher) check() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// Removed or mid-rename, the next event will pick up the new file.
+		return
+	}
+	sum := sha256.Sum256(data)
+	if sum == w.lastSeen {
+		return
+	}
+	w.lastSeen = sum
+	if isOwnWrite(w.path, sum) {
+		return
+	}
+
+	cfg, _, err := decode(data)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		w.onChange(nil, err)
+		return
+	}
+	slog.Info("Config file changed on disk, reloading")
+	w.onChange(cfg, nil)
+}
+
+// poll checks the file's modification time and size every pollInterval.
+func (w *watcher) poll() {
+	var lastMod time.Time
+	var lastSize int64
+	if fi, err := os.Stat(w.path); err == nil {
+		lastMod, lastSize = fi.ModTime(), fi.Size()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(w.path)
+			if err != nil {
+				continue
+			}
+			if fi.ModTime().Equal(lastMod) && fi.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = fi.ModTime(), fi.Size()
+			w.check()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package config
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchNative watches the config directory with inotify. The directory is
+// watched rather than the file because saves replace the file by renaming.
+func (w *watcher) watchNative() bool {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		slog.Warn("inotify unavailable, polling config instead", "err", err)
+		return false
+	}
+	dir, name := filepath.Split(w.path)
+	if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO|unix.IN_CREATE); err != nil {
+		unix.Close(fd)
+		slog.Warn("inotify watch failed, polling config instead", "err", err)
+		return false
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, 4096)
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-pending:
+				pending = nil
+				w.check()
+				continue
+			default:
+			}
+
+			// Wake up regularly to notice stop and the settle timer.
+			n, err := unix.Poll(fds, 100)
+			if err != nil && err != unix.EINTR {
+				slog.Warn("inotify poll failed, polling config instead", "err", err)
+				go w.poll()
+				return
+			}
+			if n <= 0 {
+				continue
+			}
+			n, err = unix.Read(fd, buf)
+			if err != nil || n < unix.SizeofInotifyEvent {
+				continue
+			}
+			for off := 0; off+unix.SizeofInotifyEvent <= n; {
+				ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+				off += unix.SizeofInotifyEvent + int(ev.Len)
+				if unix.ByteSliceToString(nameBytes) == name && pending == nil {
+					pending = time.After(settleDelay)
+				}
+			}
+		}
+	}()
+	return true
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package config
+
+// watchNative is not implemented on this platform, Watch polls instead.
+func (w *watcher) watchNative() bool {
+	return false
+}
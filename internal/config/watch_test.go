@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReportsExternalEditsOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := saveFile(path, DefaultConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan *Config, 4)
+	stop := Watch(path, func(cfg *Config, err error) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		changes <- cfg
+	})
+	defer stop()
+
+	// Our own save must not come back as a change.
+	own := DefaultConfig()
+	own.DurationMinutes = 15
+	if err := saveFile(path, own); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-changes:
+		t.Fatalf("own write reported as change: %+v", cfg)
+	case <-time.After(pollInterval + time.Second):
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 1, "duration_minutes": 45}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-changes:
+		if cfg.DurationMinutes != 45 {
+			t.Errorf("DurationMinutes = %d, want 45", cfg.DurationMinutes)
+		}
+	case <-time.After(pollInterval + 2*time.Second):
+		t.Fatal("external edit not reported")
+	}
+}
@@ -0,0 +1,324 @@
+// Package control lets other processes, like the ctl subcommand, drive the
+// running instance over a local socket in the config directory. A client
+// writes one JSON Request per line and reads one JSON Response line back,
+// except for the "watch" request, which is answered with a Response line on
+// every state change and every second until the client disconnects.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/timer"
+)
+
+// SocketName is the socket's file name in config.Dir.
+const SocketName = "control.sock"
+
+// ErrRunning is returned by Listen when another instance owns the socket.
+var ErrRunning = errors.New("another instance is already running")
+
+// Commands are the values Request.Command accepts.
+var Commands = []string{"status", "start", "stop", "toggle", "reset", "snooze", "pause", "resume", "toggle-pause"}
+
+type Request struct {
+	Command string `json:"command"`
+	Minutes int    `json:"minutes,omitempty"` // For snooze, 0 uses snooze_minutes
+}
+
+type Status struct {
+	State            string `json:"state"` // "stopped", "running", "alerting", "paused" or "break"
+	RemainingSeconds int    `json:"remaining_seconds"`
+	DurationSeconds  int    `json:"duration_seconds"`
+	Profile          string `json:"profile"`
+	// Phase is "work", "short_break" or "long_break" in Pomodoro mode, Cycle
+	// counts work phases from 1 to Cycles.
+	Phase  string `json:"phase,omitempty"`
+	Cycle  int    `json:"cycle,omitempty"`
+	Cycles int    `json:"cycles,omitempty"`
+	// BreakSeconds is how long the current break has lasted, see config.Breaks.
+	BreakSeconds int `json:"break_seconds,omitempty"`
+	// Merged and HeldToday count the alerts config.AlertLimit held back,
+	// Capped is true once its daily limit is reached.
+	Merged    int  `json:"merged,omitempty"`
+	HeldToday int  `json:"held_today,omitempty"`
+	Capped    bool `json:"capped,omitempty"`
+}
+
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+func SocketPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SocketName), nil
+}
+
+type Server struct {
+	ln    net.Listener
+	timer *timer.Manager
+	cfg   *config.Live
+
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+// Listen opens the socket and serves requests against tm, with the settings
+// currently in cfg, until Close. A socket left behind by an instance that
+// crashed is replaced.
+func Listen(tm *timer.Manager, cfg *config.Live) (*Server, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		if conn, dialErr := net.DialTimeout("unix", path, time.Second); dialErr == nil {
+			conn.Close()
+			return nil, ErrRunning
+		}
+		// Nobody answers, the socket is stale.
+		os.Remove(path)
+		if ln, err = net.Listen("unix", path); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	s := &Server{ln: ln, timer: tm, cfg: cfg, watchers: map[chan struct{}]struct{}{}}
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting connections and removes the socket.
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+// Notify sends the current status to watchers right away instead of at their
+// next tick. It is meant for the timer callbacks and does nothing on a nil
+// Server, so they can call it before or without Listen.
+func (s *Server) Notify() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("Control socket failed", "err", err)
+			}
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else if req.Command == "watch" {
+			s.watch(enc)
+			return
+		} else {
+			resp = s.Do(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Do runs one request, as if it came in over the socket. Watch requests are
+// not supported, see Subscribe.
+func (s *Server) Do(req Request) Response {
+	tm := s.timer
+	switch req.Command {
+	case "status":
+	case "start":
+		switch tm.GetState() {
+		case timer.StateStopped:
+			tm.Start(s.cfg.Load().Duration())
+		case timer.StatePaused:
+			tm.Resume()
+		}
+	case "stop":
+		tm.Stop()
+	case "toggle":
+		tm.Toggle()
+	case "reset":
+		tm.Reset()
+	case "snooze":
+		minutes := req.Minutes
+		if minutes <= 0 {
+			minutes = s.cfg.Load().SnoozeMinutes
+		}
+		tm.Snooze(time.Duration(minutes) * time.Minute)
+	case "pause":
+		tm.Pause()
+	case "resume":
+		tm.Resume()
+	case "toggle-pause":
+		tm.TogglePause()
+	default:
+		return Response{Error: fmt.Sprintf("unknown command %q", req.Command)}
+	}
+	status := s.Status()
+	return Response{OK: true, Status: &status}
+}
+
+// Subscribe returns a channel that receives a value on every Notify, and a
+// function to stop the subscription. Notifications coalesce if not received.
+func (s *Server) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.watchers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// watch writes the status every second and on every Notify until a write fails.
+func (s *Server) watch(enc *json.Encoder) {
+	ch, cancel := s.Subscribe()
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		status := s.Status()
+		if err := enc.Encode(Response{OK: true, Status: &status}); err != nil {
+			return
+		}
+		select {
+		case <-ch:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status reports the timer's current state.
+func (s *Server) Status() Status {
+	cfg := s.cfg.Load()
+	st := Status{
+		State:            s.timer.GetState().String(),
+		RemainingSeconds: int(s.timer.TimeRemaining().Round(time.Second).Seconds()),
+		DurationSeconds:  int(cfg.Duration().Seconds()),
+		Profile:          cfg.ActiveProfile,
+		BreakSeconds:     int(s.timer.BreakElapsed().Seconds()),
+	}
+	held := s.timer.Held()
+	st.Merged, st.HeldToday, st.Capped = held.Merged, held.Today, held.Capped
+	if phase, cycle := s.timer.Phase(); phase != timer.PhaseNone {
+		work, shortBreak, longBreak := cfg.Pomodoro.Durations()
+		length := map[timer.Phase]time.Duration{
+			timer.PhaseWork:       work,
+			timer.PhaseShortBreak: shortBreak,
+			timer.PhaseLongBreak:  longBreak,
+		}[phase]
+		st.DurationSeconds = int(length.Seconds())
+		st.Phase = phase.String()
+		st.Cycle = cycle
+		st.Cycles = cfg.Pomodoro.CyclesBeforeLongBreak
+	}
+	return st
+}
+
+// Send sends one request to the running instance and returns its response.
+// A response that is not OK is returned as an error.
+func Send(req Request) (*Response, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("no running instance: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Watch streams the running instance's status to fn until the connection
+// ends, which is reported as an error.
+func Watch(fn func(Status)) error {
+	path, err := SocketPath()
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("no running instance: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Command: "watch"}); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		// Updates come at least every second, silence means the instance hangs.
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			return err
+		}
+		if resp.Status != nil {
+			fn(*resp.Status)
+		}
+	}
+}
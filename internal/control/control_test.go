@@ -0,0 +1,109 @@
+package control
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/timer"
+)
+
+func listen(t *testing.T) (*Server, *timer.Manager) {
+	t.Helper()
+	config.SetDir(t.TempDir())
+	t.Cleanup(func() { config.SetDir("") })
+
+	tm := timer.NewManager(nil, nil, nil, nil)
+	s, err := Listen(tm, config.NewLive(config.DefaultConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, tm
+}
+
+func TestCommands(t *testing.T) {
+	_, tm := listen(t)
+
+	resp, err := Send(Request{Command: "start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status.State != "running" || resp.Status.DurationSeconds != 30*60 {
+		t.Errorf("after start: %+v", resp.Status)
+	}
+
+	if _, err := Send(Request{Command: "snooze", Minutes: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if rem := tm.TimeRemaining(); rem.Minutes() > 2 {
+		t.Errorf("after snooze 2: %v remaining", rem)
+	}
+
+	resp, err = Send(Request{Command: "pause"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status.State != "paused" || resp.Status.RemainingSeconds == 0 {
+		t.Errorf("after pause: %+v", resp.Status)
+	}
+
+	if _, err := Send(Request{Command: "explode"}); err == nil {
+		t.Error("unknown command accepted")
+	}
+}
+
+func TestSecondInstance(t *testing.T) {
+	listen(t)
+	if _, err := Listen(timer.NewManager(nil, nil, nil, nil), config.NewLive(config.DefaultConfig())); !errors.Is(err, ErrRunning) {
+		t.Errorf("second Listen = %v, want ErrRunning", err)
+	}
+}
+
+func TestStaleSocketReplaced(t *testing.T) {
+	dir := t.TempDir()
+	config.SetDir(dir)
+	t.Cleanup(func() { config.SetDir("") })
+
+	// A socket nobody listens on, as left by a crash.
+	ln, err := net.Listen("unix", filepath.Join(dir, SocketName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	if _, err := os.Stat(filepath.Join(dir, SocketName)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Listen(timer.NewManager(nil, nil, nil, nil), config.NewLive(config.DefaultConfig()))
+	if err != nil {
+		t.Fatalf("Listen over stale socket: %v", err)
+	}
+	s.Close()
+}
+
+func TestWatch(t *testing.T) {
+	s, tm := listen(t)
+
+	states := make(chan string, 10)
+	go Watch(func(st Status) { states <- st.State })
+
+	if got := <-states; got != "stopped" {
+		t.Fatalf("first update = %q, want stopped", got)
+	}
+	tm.Start(time.Minute)
+	s.Notify()
+	select {
+	case got := <-states:
+		if got != "running" {
+			t.Errorf("after Notify = %q, want running", got)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Notify did not send an update before the next tick")
+	}
+}
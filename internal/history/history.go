@@ -0,0 +1,170 @@
+// Package history records what happened to the reminder, one JSON object per
+// line in a file in the config directory. Entries older than maxAge are
+// dropped when the file is opened.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/config"
+)
+
+// FileName is the history's file name in config.Dir.
+const FileName = "history.jsonl"
+
+// maxAge is how long entries are kept.
+const maxAge = 90 * 24 * time.Hour
+
+// Entry kinds.
+const (
+	// KindBreak is a break taken after an alert, Seconds is its length.
+	KindBreak = "break"
+	// KindAck is an acknowledged alert. Seconds is the time from the start
+	// of the countdown to the acknowledgement, Delay the time from the
+	// alert to it, and Snoozes how often the alert was snoozed before.
+	KindAck = "acknowledge"
+	// KindSkip is an alert ended with stop, Seconds and Snoozes as for KindAck.
+	KindSkip = "skip"
+	// KindDrink is a drink logged with the chord or a tray action.
+	KindDrink = "drink"
+)
+
+// Entry is one recorded event.
+type Entry struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"`
+	Seconds int       `json:"seconds,omitempty"`
+	Delay   int       `json:"delay,omitempty"`
+	Snoozes int       `json:"snoozes,omitempty"`
+	Profile string    `json:"profile,omitempty"`
+}
+
+// Log is the history file. Its methods are safe for concurrent use, entries
+// are kept in time order even if appended out of order.
+type Log struct {
+	path string
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Open reads the history file in config.Dir, or starts an empty one if there
+// is none yet.
+func Open() (*Log, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{path: filepath.Join(dir, FileName)}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	pruned := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			slog.Warn("Skipping unreadable history entry", "err", err)
+			pruned = true
+			continue
+		}
+		if e.At.Before(cutoff) {
+			pruned = true
+			continue
+		}
+		l.entries = append(l.entries, e)
+	}
+	slices.SortStableFunc(l.entries, func(a, b Entry) int { return a.At.Compare(b.At) })
+	if pruned {
+		if err := l.rewrite(); err != nil {
+			slog.Warn("Failed to prune history", "err", err)
+		}
+	}
+	return l, nil
+}
+
+// Append records e. It does nothing on a nil Log, so callers without a
+// history need not check.
+func (l *Log) Append(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := len(l.entries)
+	for i > 0 && l.entries[i-1].At.After(e.At) {
+		i--
+	}
+	l.entries = slices.Insert(l.entries, i, e)
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recent returns up to n of the latest entries of the given kinds, oldest first.
+func (l *Log) Recent(n int, kinds ...string) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out []Entry
+	for i := len(l.entries) - 1; i >= 0 && len(out) < n; i-- {
+		if slices.Contains(kinds, l.entries[i].Kind) {
+			out = append(out, l.entries[i])
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Since returns the entries of the given kinds from t on, oldest first.
+func (l *Log) Since(t time.Time, kinds ...string) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out []Entry
+	for _, e := range l.entries {
+		if !e.At.Before(t) && slices.Contains(kinds, e.Kind) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Internal func, writes the entries in memory over the file.
+func (l *Log) rewrite() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range l.entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return config.WriteFileAtomic(l.path, buf.Bytes(), 0644)
+}
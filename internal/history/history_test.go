@@ -0,0 +1,54 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+)
+
+func TestRecentAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	config.SetDir(dir)
+	t.Cleanup(func() { config.SetDir("") })
+
+	old := `{"at":"2000-01-01T00:00:00Z","kind":"break","seconds":60}` + "\n" + "not json\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Recent(10, KindBreak); len(got) != 0 {
+		t.Fatalf("old and unreadable entries kept: %+v", got)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, secs := range []int{60, 120, 180} {
+		if err := l.Append(Entry{At: now.Add(time.Duration(i) * time.Minute), Kind: KindBreak, Seconds: secs}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Append(Entry{At: now, Kind: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := l.Recent(2, KindBreak)
+	if len(got) != 2 || got[0].Seconds != 120 || got[1].Seconds != 180 {
+		t.Errorf("Recent(2, break) = %+v, want the 120s and 180s breaks in order", got)
+	}
+}
+
+func TestNilLogAppend(t *testing.T) {
+	var l *Log
+	if err := l.Append(Entry{At: time.Now(), Kind: KindDrink}); err != nil {
+		t.Errorf("Append on a nil Log = %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package hooks runs the user's commands on timer events.
+package hooks
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Timeout is how long a hook may run before it is killed.
+const Timeout = time.Minute
+
+// Run starts command through the shell without waiting for it. The command
+// gets HYDRA_EVENT=event in its environment along with env, and its output
+// is logged if it fails. An empty command does nothing.
+func Run(event, command string, env ...string) {
+	if command == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		defer cancel()
+
+		cmd := shellCommand(ctx, command)
+		cmd.Env = append(os.Environ(), "HYDRA_EVENT="+event)
+		cmd.Env = append(cmd.Env, env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			slog.Warn("Hook failed", "event", event, "err", err, "output", string(out))
+		}
+	}()
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package hooks
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs command through cmd.exe. The command line is passed as is,
+// cmd.exe does its own parsing, and no console window is shown.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:    `cmd.exe /S /C "` + command + `"`,
+		HideWindow: true,
+	}
+	return cmd
+}
@@ -0,0 +1,141 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+#include <sys/select.h>
+#include <sys/time.h>
+#include <time.h>
+
+static long elapsedMs(struct timespec *start) {
+	struct timespec now;
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	return (now.tv_sec - start->tv_sec) * 1000 + (now.tv_nsec - start->tv_nsec) / 1000000;
+}
+
+// grabNextKey grabs the whole keyboard and returns the keysym of the next
+// non-modifier key press, or 0 when nothing was pressed before the timeout.
+static unsigned long grabNextKey(int timeoutMs) {
+	Display *dpy = XOpenDisplay(NULL);
+	if (dpy == NULL) {
+		return 0;
+	}
+
+	struct timespec start;
+	clock_gettime(CLOCK_MONOTONIC, &start);
+
+	// The leader combination may still be held down, in which case the
+	// passive grab of the hotkey is active and XGrabKeyboard reports
+	// AlreadyGrabbed until it is released.
+	Window root = DefaultRootWindow(dpy);
+	int grabbed = 0;
+	while (elapsedMs(&start) < timeoutMs) {
+		if (XGrabKeyboard(dpy, root, False, GrabModeAsync, GrabModeAsync, CurrentTime) == GrabSuccess) {
+			grabbed = 1;
+			break;
+		}
+		struct timespec pause = {0, 10 * 1000000};
+		nanosleep(&pause, NULL);
+	}
+	if (!grabbed) {
+		XCloseDisplay(dpy);
+		return 0;
+	}
+
+	unsigned long result = 0;
+	int fd = ConnectionNumber(dpy);
+	while (result == 0) {
+		long left = timeoutMs - elapsedMs(&start);
+		if (left <= 0) {
+			break;
+		}
+		if (XPending(dpy) == 0) {
+			fd_set fds;
+			FD_ZERO(&fds);
+			FD_SET(fd, &fds);
+			struct timeval tv = {left / 1000, (left % 1000) * 1000};
+			if (select(fd + 1, &fds, NULL, NULL, &tv) <= 0) {
+				continue;
+			}
+		}
+		while (XPending(dpy) > 0) {
+			XEvent ev;
+			XNextEvent(dpy, &ev);
+			if (ev.type != KeyPress) {
+				continue;
+			}
+			KeySym sym = XLookupKeysym(&ev.xkey, 0);
+			if (sym != NoSymbol && !IsModifierKey(sym)) {
+				result = sym;
+				break;
+			}
+		}
+	}
+
+	XUngrabKeyboard(dpy, CurrentTime);
+	XCloseDisplay(dpy);
+	return result;
+}
+*/
+import "C"
+
+import (
+	"log/slog"
+	"time"
+)
+
+var currentChord *keyGrab
+
+// RegisterChord registers a leader combination. After it is pressed the keyboard
+// is grabbed until the next key press or the timeout, whichever comes first.
+func RegisterChord(modifiers uint32, leader uint32, timeout time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if currentChord != nil {
+		currentChord.release()
+		currentChord = nil
+	}
+
+	timeoutMs := C.int(chordTimeout(timeout).Milliseconds())
+
+	g, err := grab(mapWindowsModifiers(modifiers), mapWindowsVK(leader), func() {
+		sym := uint32(C.grabNextKey(timeoutMs))
+		if sym == 0 {
+			slog.Debug("Chord timed out")
+			return
+		}
+		// X11 keysyms for a-z are lowercase ASCII, chordKeys uses VK codes.
+		if sym >= 'a' && sym <= 'z' {
+			sym -= 0x20
+		}
+		action, ok := chordKeys[sym]
+		if !ok {
+			slog.Debug("Chord: no action bound to key", "keysym", sym)
+			return
+		}
+		if chordCallback != nil {
+			go chordCallback(action)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	currentChord = g
+	return nil
+}
+
+// UnregisterChord unregisters the chord leader, if any.
+func UnregisterChord() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if currentChord != nil {
+		currentChord.release()
+		currentChord = nil
+	}
+}
@@ -0,0 +1,134 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	procSetTimer  = user32.NewProc("SetTimer")
+	procKillTimer = user32.NewProc("KillTimer")
+)
+
+const (
+	WM_TIMER     = 0x0113
+	MOD_NOREPEAT = 0x4000
+
+	chordLeaderID = 2
+	// Action keys are registered twice, bare and with the leader modifiers
+	// still held, starting at this id.
+	chordActionBaseID = 10
+)
+
+var (
+	chordThreadId uint32
+	chordDoneCh   chan struct{}
+)
+
+// RegisterChord registers a leader combination. After it is pressed the action
+// keys are registered as temporary hotkeys until one of them is pressed or the
+// timeout expires, whichever comes first.
+func RegisterChord(modifiers uint32, leader uint32, timeout time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stopLoop(&chordThreadId, &chordDoneCh)
+
+	timeoutMs := uintptr(chordTimeout(timeout).Milliseconds())
+
+	// Index the action keys so message ids map back to them.
+	var keys []uint32
+	for k := range chordKeys {
+		keys = append(keys, k)
+	}
+
+	var armed bool
+	var timerID uintptr
+
+	disarm := func() {
+		if !armed {
+			return
+		}
+		for i := range keys {
+			procUnregisterHotKey.Call(0, uintptr(chordActionBaseID+2*i))
+			procUnregisterHotKey.Call(0, uintptr(chordActionBaseID+2*i+1))
+		}
+		if timerID != 0 {
+			procKillTimer.Call(0, timerID)
+			timerID = 0
+		}
+		armed = false
+	}
+
+	arm := func() {
+		if armed {
+			return
+		}
+		for i, k := range keys {
+			// Another application may hold the key, the chord then cannot
+			// reach that action.
+			if ret, _, err := procRegisterHotKey.Call(0, uintptr(chordActionBaseID+2*i), MOD_NOREPEAT, uintptr(k)); ret == 0 {
+				slog.Warn("Failed to register chord key", "key", string(rune(k)), "err", err)
+			}
+			if ret, _, err := procRegisterHotKey.Call(0, uintptr(chordActionBaseID+2*i+1), uintptr(modifiers)|MOD_NOREPEAT, uintptr(k)); ret == 0 {
+				slog.Warn("Failed to register chord key with the leader modifiers", "key", string(rune(k)), "err", err)
+			}
+		}
+		timerID, _, _ = procSetTimer.Call(0, 0, timeoutMs, 0)
+		armed = true
+	}
+
+	res := startLoop(
+		func() error {
+			ret, _, err := procRegisterHotKey.Call(0, chordLeaderID, uintptr(modifiers), uintptr(leader))
+			if ret == 0 {
+				return fmt.Errorf("RegisterHotKey failed for chord leader: %v", err)
+			}
+			return nil
+		},
+		func() {
+			disarm()
+			procUnregisterHotKey.Call(0, chordLeaderID)
+		},
+		func(m *msg) {
+			switch m.Message {
+			case WM_HOTKEY:
+				if m.WParam == chordLeaderID {
+					arm()
+					return
+				}
+				idx := int(m.WParam) - chordActionBaseID
+				if !armed || idx < 0 || idx/2 >= len(keys) {
+					return
+				}
+				disarm()
+				if chordCallback != nil {
+					go chordCallback(chordKeys[keys[idx/2]])
+				}
+			case WM_TIMER:
+				if armed && m.WParam == timerID {
+					disarm()
+				}
+			}
+		},
+	)
+	if res.err != nil {
+		return res.err
+	}
+
+	chordThreadId = res.threadId
+	chordDoneCh = res.doneCh
+
+	return nil
+}
+
+// UnregisterChord unregisters the chord leader, if any.
+func UnregisterChord() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stopLoop(&chordThreadId, &chordDoneCh)
+}
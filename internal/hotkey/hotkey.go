@@ -0,0 +1,53 @@
+package hotkey
+
+import "time"
+
+// Action is what the key pressed after the chord leader selects.
+type Action int
+
+const (
+	ActionReset Action = iota
+	ActionSnooze
+	ActionDrink
+	ActionPause
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionReset:
+		return "reset"
+	case ActionSnooze:
+		return "snooze"
+	case ActionDrink:
+		return "drink"
+	case ActionPause:
+		return "pause"
+	}
+	return "unknown"
+}
+
+// chordKeys maps follow-up keys (Windows virtual key codes) to their actions.
+var chordKeys = map[uint32]Action{
+	'R': ActionReset,
+	'S': ActionSnooze,
+	'D': ActionDrink,
+	'P': ActionPause,
+}
+
+// DefaultChordTimeout is used when RegisterChord is given a non-positive timeout.
+const DefaultChordTimeout = 1500 * time.Millisecond
+
+var chordCallback func(Action)
+
+// InitChord sets the callback executed when a chord leader is followed by one of
+// the action keys R (reset), S (snooze), D (log drink) or P (pause).
+func InitChord(callback func(Action)) {
+	chordCallback = callback
+}
+
+func chordTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultChordTimeout
+	}
+	return timeout
+}
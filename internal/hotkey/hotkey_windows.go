@@ -4,6 +4,7 @@ package hotkey
 
 import (
 	"fmt"
+	"log/slog"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -63,13 +64,47 @@ func Register(modifiers uint32, key uint32) error {
 	defer mu.Unlock()
 
 	// Stop any existing loop fully before proceeding
-	if loopThreadId != 0 {
-		procPostThreadMessageW.Call(uintptr(loopThreadId), WM_QUIT, 0, 0)
-		<-loopDoneCh // Wait for thread to exit
-		loopThreadId = 0
-		loopDoneCh = nil
+	stopLoop(&loopThreadId, &loopDoneCh)
+
+	res := startLoop(
+		func() error {
+			ret, _, err := procRegisterHotKey.Call(
+				0,                  // hwnd
+				uintptr(hotkeyID),  // id
+				uintptr(modifiers), // fsModifiers
+				uintptr(key),       // vk
+			)
+			if ret == 0 {
+				return fmt.Errorf("RegisterHotKey failed: %v", err)
+			}
+			return nil
+		},
+		func() {
+			procUnregisterHotKey.Call(0, uintptr(hotkeyID))
+		},
+		func(m *msg) {
+			if m.Message == WM_HOTKEY && m.WParam == hotkeyID {
+				if hotkeyCallback != nil {
+					go hotkeyCallback()
+				}
+			}
+		},
+	)
+	if res.err != nil {
+		return res.err
 	}
 
+	loopThreadId = res.threadId
+	loopDoneCh = res.doneCh
+
+	slog.Debug("Hotkey registered", "modifiers", fmt.Sprintf("0x%x", modifiers), "key", fmt.Sprintf("0x%x", key))
+	return nil
+}
+
+// startLoop runs a Windows message loop on a dedicated OS thread. register is
+// called on that thread before the loop starts, unregister when it ends, and
+// handle for every message received.
+func startLoop(register func() error, unregister func(), handle func(m *msg)) registerResult {
 	resCh := make(chan registerResult, 1)
 
 	go func() {
@@ -78,26 +113,18 @@ func Register(modifiers uint32, key uint32) error {
 		defer runtime.UnlockOSThread()
 
 		tid := windows.GetCurrentThreadId()
-
-		ret, _, err := procRegisterHotKey.Call(
-			0,                  // hwnd
-			uintptr(hotkeyID),  // id
-			uintptr(modifiers), // fsModifiers
-			uintptr(key),       // vk
-		)
-
 		done := make(chan struct{})
 
-		if ret == 0 {
+		if err := register(); err != nil {
 			close(done)
-			resCh <- registerResult{err: fmt.Errorf("RegisterHotKey failed: %v", err)}
+			resCh <- registerResult{err: err}
 			return
 		}
 
 		resCh <- registerResult{threadId: tid, doneCh: done, err: nil}
 
 		defer func() {
-			procUnregisterHotKey.Call(0, uintptr(hotkeyID))
+			unregister()
 			close(done)
 		}()
 
@@ -114,33 +141,26 @@ func Register(modifiers uint32, key uint32) error {
 				return
 			}
 
-			if m.Message == WM_HOTKEY && m.WParam == hotkeyID {
-				if hotkeyCallback != nil {
-					go hotkeyCallback()
-				}
-			}
+			handle(&m)
 		}
 	}()
 
-	res := <-resCh
-	if res.err != nil {
-		return res.err
-	}
-
-	loopThreadId = res.threadId
-	loopDoneCh = res.doneCh
+	return <-resCh
+}
 
-	return nil
+// stopLoop asks a running message loop to quit and waits for it to clean up.
+func stopLoop(threadId *uint32, doneCh *chan struct{}) {
+	if *threadId != 0 {
+		procPostThreadMessageW.Call(uintptr(*threadId), WM_QUIT, 0, 0)
+		<-*doneCh // Wait for thread to cleanly unregister
+		*threadId = 0
+		*doneCh = nil
+	}
 }
 
 func Unregister() {
 	mu.Lock()
 	defer mu.Unlock()
 
-	if loopThreadId != 0 {
-		procPostThreadMessageW.Call(uintptr(loopThreadId), WM_QUIT, 0, 0)
-		<-loopDoneCh // Wait for thread to cleanly unregister
-		loopThreadId = 0
-		loopDoneCh = nil
-	}
+	stopLoop(&loopThreadId, &loopDoneCh)
 }
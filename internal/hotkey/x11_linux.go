@@ -0,0 +1,166 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+
+#include <X11/Xlib.h>
+#include <sys/select.h>
+
+static int grabError;
+
+static int onGrabError(Display *dpy, XErrorEvent *ev) {
+	grabError = ev->error_code;
+	return 0;
+}
+
+// grabKey grabs the key on the root window with the modifiers, once for every
+// combination of the Caps Lock and Num Lock states so they do not get in the
+// way. It returns 0, -1 if the key has no keycode, or the X error, usually
+// BadAccess when another client already grabbed the combination.
+static int grabKey(Display *dpy, unsigned int mods, unsigned long sym) {
+	KeyCode code = XKeysymToKeycode(dpy, sym);
+	if (code == 0) {
+		return -1;
+	}
+	unsigned int locks[] = {0, LockMask, Mod2Mask, LockMask | Mod2Mask};
+	Window root = DefaultRootWindow(dpy);
+
+	grabError = 0;
+	XErrorHandler previous = XSetErrorHandler(onGrabError);
+	for (int i = 0; i < 4; i++) {
+		XGrabKey(dpy, code, mods | locks[i], root, False, GrabModeAsync, GrabModeAsync);
+	}
+	XSync(dpy, False);
+	XSetErrorHandler(previous);
+	return grabError;
+}
+
+// waitKeyPress blocks until the grabbed key is pressed, returning 1, or until
+// stopFd becomes readable, returning 0.
+static int waitKeyPress(Display *dpy, int stopFd) {
+	int fd = ConnectionNumber(dpy);
+	int maxFd = fd > stopFd ? fd : stopFd;
+	for (;;) {
+		while (XPending(dpy) > 0) {
+			XEvent ev;
+			XNextEvent(dpy, &ev);
+			if (ev.type == KeyPress) {
+				return 1;
+			}
+		}
+		fd_set fds;
+		FD_ZERO(&fds);
+		FD_SET(fd, &fds);
+		FD_SET(stopFd, &fds);
+		if (select(maxFd + 1, &fds, NULL, NULL, NULL) < 0) {
+			continue;
+		}
+		if (FD_ISSET(stopFd, &fds)) {
+			return 0;
+		}
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/sys/unix"
+)
+
+// X11 modifier masks.
+const (
+	x11ShiftMask   = 1 << 0
+	x11ControlMask = 1 << 2
+	x11Mod1Mask    = 1 << 3 // Alt
+	x11Mod4Mask    = 1 << 6 // Super
+)
+
+// errNoDisplay is returned when there is no X server to grab keys from, for
+// example over SSH or in headless mode on a server.
+var errNoDisplay = errors.New("hotkeys need an X11 display, none is available")
+
+// keyGrab is a passive grab of one key combination, held on its own display
+// connection until release is called.
+type keyGrab struct {
+	dpy  *C.Display
+	stop [2]int // pipe, closing the write end ends the wait loop
+	done chan struct{}
+}
+
+// grab grabs the combination and calls onPress, on the grab's own goroutine,
+// every time it is pressed.
+func grab(modifiers uint32, keysym uint32, onPress func()) (*keyGrab, error) {
+	dpy := C.XOpenDisplay(nil)
+	if dpy == nil {
+		return nil, errNoDisplay
+	}
+	switch rc := C.grabKey(dpy, C.uint(modifiers), C.ulong(keysym)); rc {
+	case 0:
+	case -1:
+		C.XCloseDisplay(dpy)
+		return nil, fmt.Errorf("no key for keysym 0x%x on this keyboard", keysym)
+	default:
+		C.XCloseDisplay(dpy)
+		return nil, fmt.Errorf("key combination is already taken by another application (X error %d)", rc)
+	}
+
+	g := &keyGrab{dpy: dpy, done: make(chan struct{})}
+	if err := unix.Pipe2(g.stop[:], unix.O_CLOEXEC); err != nil {
+		C.XCloseDisplay(dpy)
+		return nil, err
+	}
+
+	slog.Debug("Key grabbed", "modifiers", fmt.Sprintf("0x%x", modifiers), "keysym", fmt.Sprintf("0x%x", keysym))
+	go func() {
+		defer close(g.done)
+		for C.waitKeyPress(g.dpy, C.int(g.stop[0])) == 1 {
+			onPress()
+		}
+	}()
+	return g, nil
+}
+
+// release ungrabs the combination and waits for the goroutine to finish.
+func (g *keyGrab) release() {
+	unix.Close(g.stop[1])
+	<-g.done
+	unix.Close(g.stop[0])
+	// Closing the connection releases its grabs.
+	C.XCloseDisplay(g.dpy)
+}
+
+// mapWindowsModifiers converts a Windows modifier bitmask to X11 modifier masks.
+// Windows: MOD_ALT=0x0001, MOD_CONTROL=0x0002, MOD_SHIFT=0x0004, MOD_WIN=0x0008
+func mapWindowsModifiers(modifiers uint32) uint32 {
+	var mods uint32
+	if modifiers&0x0001 != 0 { // MOD_ALT
+		mods |= x11Mod1Mask
+	}
+	if modifiers&0x0002 != 0 { // MOD_CONTROL
+		mods |= x11ControlMask
+	}
+	if modifiers&0x0004 != 0 { // MOD_SHIFT
+		mods |= x11ShiftMask
+	}
+	if modifiers&0x0008 != 0 { // MOD_WIN
+		mods |= x11Mod4Mask
+	}
+	return mods
+}
+
+// mapWindowsVK converts a Windows virtual key code (A-Z: 0x41-0x5A) to an X11 keysym.
+// X11 keysyms for a-z are 0x0061-0x007a (lowercase ASCII).
+func mapWindowsVK(vk uint32) uint32 {
+	if vk >= 0x41 && vk <= 0x5A {
+		// Convert uppercase VK code to lowercase X11 keysym
+		return vk + 0x20 // 'A'(0x41) -> 'a'(0x61)
+	}
+	// Digits have the same codes in both
+	return vk
+}
@@ -0,0 +1,234 @@
+// Package httpapi serves the control commands over HTTP on 127.0.0.1, for
+// tools that cannot use the control socket such as Stream Deck plugins and
+// home dashboards. Every request needs the token in TokenFile, either as a
+// bearer token or as the access_token query parameter for EventSource.
+package httpapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+)
+
+// TokenFile is the token's file name in config.Dir.
+const TokenFile = "api-token"
+
+// Token returns the API token, creating it on first use. The file is only
+// readable by the user.
+func Token() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, TokenFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Service runs the HTTP server while it is enabled in the config.
+type Service struct {
+	ctl     *control.Server
+	metrics http.Handler
+
+	mu   sync.Mutex
+	srv  *http.Server
+	port int
+}
+
+// New returns a stopped Service for ctl. metrics, if not nil, is served at
+// /metrics for Prometheus.
+func New(ctl *control.Server, metrics http.Handler) *Service {
+	return &Service{ctl: ctl, metrics: metrics}
+}
+
+// Apply starts, stops or moves the server to match the settings. Failures are
+// logged, the app works without the API. Apply does nothing on a nil Service.
+func (s *Service) Apply(enabled bool, port int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.srv != nil && (!enabled || port != s.port) {
+		s.srv.Close()
+		s.srv = nil
+		slog.Info("HTTP API stopped")
+	}
+	if !enabled || s.srv != nil {
+		return
+	}
+
+	token, err := Token()
+	if err != nil {
+		slog.Error("HTTP API unavailable, no token", "err", err)
+		return
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Error("HTTP API unavailable", "err", err)
+		return
+	}
+	srv := &http.Server{
+		Handler:           s.Handler(token),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP API failed", "err", err)
+		}
+	}()
+	s.srv = srv
+	s.port = port
+	slog.Info("HTTP API listening", "url", "http://"+addr)
+}
+
+// Handler returns the API's routes, requiring token on every request.
+func (s *Service) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.ctl.Status())
+	})
+	mux.HandleFunc("GET /events", s.events)
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics)
+	}
+	for _, cmd := range control.Commands {
+		if cmd == "status" {
+			continue
+		}
+		mux.HandleFunc("POST /"+cmd, func(w http.ResponseWriter, r *http.Request) {
+			s.command(w, r, cmd)
+		})
+	}
+	return requireToken(token, mux)
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			got = r.URL.Query().Get("access_token")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="hydra-reminder"`)
+			writeJSON(w, http.StatusUnauthorized, errorBody{"missing or wrong token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// command runs cmd. Snooze takes the minutes from a ?minutes= parameter or a
+// {"minutes": n} body.
+func (s *Service) command(w http.ResponseWriter, r *http.Request, cmd string) {
+	req := control.Request{Command: cmd}
+	if v := r.URL.Query().Get("minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			writeJSON(w, http.StatusBadRequest, errorBody{fmt.Sprintf("invalid minutes %q", v)})
+			return
+		}
+		req.Minutes = minutes
+	} else if r.ContentLength != 0 {
+		var body struct {
+			Minutes int `json:"minutes"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody{fmt.Sprintf("invalid body: %v", err)})
+			return
+		}
+		req.Minutes = body.Minutes
+	}
+
+	resp := s.ctl.Do(req)
+	if !resp.OK {
+		writeJSON(w, http.StatusBadRequest, errorBody{resp.Error})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp.Status)
+}
+
+// events is a Server-Sent Events stream with a "status" event on connect and
+// on every timer state change.
+func (s *Service) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorBody{"streaming unsupported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	changed, cancel := s.ctl.Subscribe()
+	defer cancel()
+	// Comments keep proxies and idle timeouts from closing the stream.
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		data, _ := json.Marshal(s.ctl.Status())
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		for waiting := true; waiting; {
+			select {
+			case <-changed:
+				waiting = false
+			case <-keepalive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+	"hydra-reminder/internal/timer"
+)
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) (*httptest.Server, *control.Server, *timer.Manager) {
+	t.Helper()
+	config.SetDir(t.TempDir())
+	t.Cleanup(func() { config.SetDir("") })
+
+	tm := timer.NewManager(nil, nil, nil, nil)
+	ctl, err := control.Listen(tm, config.NewLive(config.DefaultConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ctl.Close() })
+
+	ts := httptest.NewServer(New(ctl, nil).Handler(testToken))
+	t.Cleanup(ts.Close)
+	return ts, ctl, tm
+}
+
+func do(t *testing.T, method, url, token string) (*http.Response, control.Status) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st control.Status
+	json.NewDecoder(resp.Body).Decode(&st)
+	return resp, st
+}
+
+func TestRequiresToken(t *testing.T) {
+	ts, _, _ := newTestServer(t)
+	for _, token := range []string{"", "wrong"} {
+		if resp, _ := do(t, "GET", ts.URL+"/status", token); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, resp.StatusCode)
+		}
+	}
+	if resp, _ := do(t, "GET", ts.URL+"/status?access_token="+testToken, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("access_token parameter: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	ts, _, tm := newTestServer(t)
+
+	if resp, st := do(t, "POST", ts.URL+"/start", testToken); resp.StatusCode != http.StatusOK || st.State != "running" {
+		t.Fatalf("POST /start: %d %+v", resp.StatusCode, st)
+	}
+	if resp, _ := do(t, "POST", ts.URL+"/snooze?minutes=2", testToken); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /snooze: %d", resp.StatusCode)
+	}
+	if rem := tm.TimeRemaining(); rem > 2*time.Minute {
+		t.Errorf("after snooze 2: %v remaining", rem)
+	}
+	if resp, _ := do(t, "GET", ts.URL+"/reset", testToken); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /reset: status %d, want 405", resp.StatusCode)
+	}
+	if resp, st := do(t, "POST", ts.URL+"/stop", testToken); resp.StatusCode != http.StatusOK || st.State != "stopped" {
+		t.Errorf("POST /stop: %d %+v", resp.StatusCode, st)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts, ctl, tm := newTestServer(t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				lines <- data
+			}
+		}
+	}()
+
+	next := func() control.Status {
+		select {
+		case data := <-lines:
+			var st control.Status
+			if err := json.Unmarshal([]byte(data), &st); err != nil {
+				t.Fatal(err)
+			}
+			return st
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+		}
+		return control.Status{}
+	}
+	if st := next(); st.State != "stopped" {
+		t.Errorf("first event state = %q, want stopped", st.State)
+	}
+	tm.Start(time.Minute)
+	ctl.Notify()
+	if st := next(); st.State != "running" {
+		t.Errorf("event after start = %q, want running", st.State)
+	}
+}
+
+func TestTokenCreatedOnce(t *testing.T) {
+	dir := t.TempDir()
+	config.SetDir(dir)
+	t.Cleanup(func() { config.SetDir("") })
+
+	first, err := Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "" || first != second {
+		t.Errorf("tokens %q and %q, want the same non-empty token", first, second)
+	}
+	info, err := os.Stat(filepath.Join(dir, TokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file mode %v, want 0600", perm)
+	}
+}
@@ -0,0 +1,105 @@
+// Package logging sets up log/slog for the app. Records go to stderr and to a
+// size-rotated file in the config directory, because stderr goes nowhere for
+// Windows GUI builds and apps started by XDG autostart.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"hydra-reminder/internal/config"
+)
+
+// FileName is the log's file name in config.Dir.
+const FileName = "hydra-reminder.log"
+
+var (
+	mu    sync.Mutex
+	level slog.LevelVar
+	debug bool
+	out   io.Writer = os.Stderr
+)
+
+// Start logs to the log file as well as stderr. Without the file, logging
+// stays on stderr only.
+func Start() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	f, err := openRotating(path)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	out = tee{f}
+	install()
+	return nil
+}
+
+// Path returns the log file's path.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// SetLevel sets the lowest level logged: "debug", "info", "warn" or "error".
+// Debug also adds source locations to every record.
+func SetLevel(name string) {
+	l, err := ParseLevel(name)
+	if err != nil {
+		slog.Warn("Unknown log level, using info", "level", name)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	level.Set(l)
+	debug = l == slog.LevelDebug
+	install()
+}
+
+// ParseLevel converts a log_level value to a slog.Level, info if invalid.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// install makes a handler for the current settings the default. It also
+// takes over the standard logger, so log.Printf from libraries ends up in
+// the file too. Internal func, assumes lock is held
+func install() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
+		Level:     &level,
+		AddSource: debug,
+	})))
+}
+
+// tee writes to the log file and stderr. Errors from stderr are ignored, it
+// is often closed or invalid when the file matters most.
+type tee struct {
+	file io.Writer
+}
+
+func (t tee) Write(p []byte) (int, error) {
+	os.Stderr.Write(p)
+	return t.file.Write(p)
+}
@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+)
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	r, err := openRotating(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	// Enough for the file to be rotated keep+1 times.
+	for i := 0; i < (keep+1)*maxSize/len(line)+10; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > maxSize {
+		t.Errorf("log file is %d bytes, want at most %d", info.Size(), maxSize)
+	}
+	for i := 1; i <= keep; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", path, i)); err != nil {
+			t.Errorf("rotated file %d missing: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, keep+1)); !os.IsNotExist(err) {
+		t.Errorf("more than %d rotated files kept", keep)
+	}
+}
+
+func TestRotationKeepsExistingSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), maxSize-10), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := openRotating(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("a line longer than ten bytes\n"))
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("file left over from the last run was not rotated: %v", err)
+	}
+}
+
+func TestFailedRotationBacksOff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), maxSize), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tries := 0
+	rename = func(from, to string) error {
+		if from == path {
+			tries++
+			return errors.New("file in use")
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	r, err := openRotating(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		if _, err := r.Write([]byte("still logged\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tries != 1 {
+		t.Errorf("rotation tried %d times, want once until the retry is due", tries)
+	}
+
+	r.retryAt = time.Now()
+	rename = os.Rename
+	r.Write([]byte("rotated\n"))
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("not rotated once the retry was due: %v", err)
+	}
+}
+
+func TestStartAndSetLevel(t *testing.T) {
+	config.SetDir(t.TempDir())
+	defer config.SetDir("")
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	if err := Start(); err != nil {
+		t.Fatal(err)
+	}
+	SetLevel("warn")
+	slog.Info("hidden")
+	slog.Warn("shown", "key", "value")
+	SetLevel("debug")
+	slog.Debug("debugging")
+
+	path, _ := Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if strings.Contains(got, "hidden") {
+		t.Errorf("info record logged at warn level:\n%s", got)
+	}
+	if !strings.Contains(got, `level=WARN msg=shown key=value`) {
+		t.Errorf("warn record missing:\n%s", got)
+	}
+	if !strings.Contains(got, "msg=debugging") || !strings.Contains(got, "source=") {
+		t.Errorf("debug record missing or without source:\n%s", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+}
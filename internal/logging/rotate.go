@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// maxSize is the size at which the log file is rotated.
+	maxSize = 1 << 20
+	// keep is how many rotated files are kept, path.1 being the newest.
+	keep = 3
+	// retryRotation is how long a failed rotation waits before the next try,
+	// e.g. while another program holds the file open on Windows.
+	retryRotation = time.Minute
+)
+
+// rename is os.Rename, replaced in tests.
+var rename = os.Rename
+
+// rotatingFile is an append-only file that is renamed to path.1 once it
+// would grow past maxSize, shifting older files up to path.<keep>.
+type rotatingFile struct {
+	path string
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	retryAt time.Time // no rotation before then after one failed
+}
+
+func openRotating(path string) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Internal func, assumes lock is held
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > maxSize && !time.Now().Before(r.retryAt) {
+		if err := r.rotate(); err != nil {
+			// Keep writing to the old file rather than losing records.
+			r.retryAt = time.Now().Add(retryRotation)
+			fmt.Fprintf(os.Stderr, "log rotation failed, retrying in %v: %v\n", retryRotation, err)
+		}
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Internal func, assumes lock is held
+func (r *rotatingFile) rotate() error {
+	// Windows cannot rename an open file.
+	r.f.Close()
+	r.f = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", r.path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	renameErr := rename(r.path, r.path+".1")
+	if err := r.open(); err != nil {
+		return err
+	}
+	return renameErr
+}
@@ -0,0 +1,149 @@
+// Package metrics counts timer transitions and serves them in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"hydra-reminder/internal/timer"
+)
+
+// ackBuckets are the upper bounds, in seconds, of the time-to-acknowledge histogram.
+var ackBuckets = []float64{10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// counters are the transitions that get a counter, with its name and help.
+var counters = []struct {
+	kind timer.EventKind
+	name string
+	help string
+}{
+	{timer.EventAlert, "hydra_reminder_alerts_total", "Alerts fired."},
+	{timer.EventReset, "hydra_reminder_resets_total", "Timer resets, acknowledged or not."},
+	{timer.EventSnooze, "hydra_reminder_snoozes_total", "Snoozes."},
+	{timer.EventStop, "hydra_reminder_stops_total", "Timer stops."},
+	{timer.EventBreak, "hydra_reminder_breaks_total", "Breaks started by acknowledging an alert."},
+	{timer.EventHold, "hydra_reminder_held_alerts_total", "Alerts held back by alert_limit."},
+}
+
+var states = []timer.State{timer.StateStopped, timer.StateRunning, timer.StateAlerting, timer.StatePaused, timer.StateBreak}
+
+type Metrics struct {
+	timer *timer.Manager
+
+	mu      sync.Mutex
+	counts  map[timer.EventKind]uint64
+	alertAt time.Time // zero unless alerting
+	// Time-to-acknowledge histogram, buckets are cumulative on output only.
+	ackCounts []uint64
+	ackSum    float64
+	ackTotal  uint64
+}
+
+// New starts counting tm's transitions.
+func New(tm *timer.Manager) *Metrics {
+	m := &Metrics{
+		timer:     tm,
+		counts:    map[timer.EventKind]uint64{},
+		ackCounts: make([]uint64, len(ackBuckets)),
+	}
+	tm.Subscribe(m.observe)
+	return m
+}
+
+// observe is called with the timer's lock held, it must not call into it.
+func (m *Metrics) observe(ev timer.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counts[ev.Kind]++
+	if ev.Kind == timer.EventAlert {
+		m.alertAt = ev.At
+		return
+	}
+	// Anything that ends the alert acknowledges it: reset, snooze, stop or start.
+	if ev.From == timer.StateAlerting && !m.alertAt.IsZero() {
+		m.observeAck(ev.At.Sub(m.alertAt).Seconds())
+		m.alertAt = time.Time{}
+	}
+}
+
+// Internal func, assumes lock is held
+func (m *Metrics) observeAck(seconds float64) {
+	m.ackSum += seconds
+	m.ackTotal++
+	for i, le := range ackBuckets {
+		if seconds <= le {
+			m.ackCounts[i]++
+			return
+		}
+	}
+}
+
+// ServeHTTP writes the metrics for a Prometheus scrape.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	// Read the timer before taking our lock, observe runs in the opposite order.
+	state := m.timer.GetState()
+	remaining := m.timer.TimeRemaining()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	for _, c := range counters {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, m.counts[c.kind])
+	}
+
+	const ack = "hydra_reminder_time_to_acknowledge_seconds"
+	fmt.Fprintf(cw, "# HELP %s Time from an alert to the reset, snooze or stop that ended it.\n# TYPE %s histogram\n", ack, ack)
+	var cumulative uint64
+	for i, le := range ackBuckets {
+		cumulative += m.ackCounts[i]
+		fmt.Fprintf(cw, "%s_bucket{le=\"%s\"} %d\n", ack, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(cw, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n",
+		ack, m.ackTotal, ack, strconv.FormatFloat(m.ackSum, 'g', -1, 64), ack, m.ackTotal)
+
+	const st = "hydra_reminder_state"
+	fmt.Fprintf(cw, "# HELP %s Current timer state, 1 for the active one.\n# TYPE %s gauge\n", st, st)
+	for _, s := range states {
+		v := 0
+		if s == state {
+			v = 1
+		}
+		fmt.Fprintf(cw, "%s{state=\"%s\"} %d\n", st, s, v)
+	}
+
+	const rem = "hydra_reminder_seconds_remaining"
+	fmt.Fprintf(cw, "# HELP %s Seconds until the next alert, 0 when stopped or alerting.\n# TYPE %s gauge\n%s %s\n",
+		rem, rem, rem, strconv.FormatFloat(remaining.Seconds(), 'f', 0, 64))
+
+	return cw.n, cw.err
+}
+
+// countingWriter keeps the first error and the byte count for WriteTo.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
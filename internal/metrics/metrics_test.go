@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/timer"
+)
+
+func TestMetrics(t *testing.T) {
+	tm := timer.NewManager(nil, nil, nil, nil)
+	m := New(tm)
+
+	alerted := make(chan struct{}, 1)
+	tm.Subscribe(func(ev timer.Event) {
+		if ev.Kind == timer.EventAlert {
+			alerted <- struct{}{}
+		}
+	})
+
+	tm.Start(10 * time.Millisecond)
+	<-alerted
+	tm.Reset()
+	tm.Snooze(time.Minute)
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"hydra_reminder_alerts_total 1\n",
+		"hydra_reminder_resets_total 1\n",
+		"hydra_reminder_snoozes_total 1\n",
+		"hydra_reminder_stops_total 0\n",
+		"hydra_reminder_time_to_acknowledge_seconds_bucket{le=\"10\"} 1\n",
+		"hydra_reminder_time_to_acknowledge_seconds_bucket{le=\"+Inf\"} 1\n",
+		"hydra_reminder_time_to_acknowledge_seconds_count 1\n",
+		"hydra_reminder_state{state=\"running\"} 1\n",
+		"hydra_reminder_state{state=\"alerting\"} 0\n",
+		"hydra_reminder_seconds_remaining 60\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package mqtt connects the app to an MQTT broker for smart-home setups. It
+// publishes the timer status as retained JSON to the state topic and runs the
+// ctl commands it receives on the command topic, either as text ("reset",
+// "snooze 10") or as JSON ({"command": "snooze", "minutes": 10}).
+//
+// With Home Assistant's MQTT discovery the app shows up as a device with
+// sensors for the state and the time left, an alert binary sensor for
+// automations such as flashing a lamp, and reset, stop and snooze buttons.
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+)
+
+// publishEvery is how often the time left is refreshed while nothing changes.
+const publishEvery = 15 * time.Second
+
+// Service keeps a broker connection while one is configured.
+type Service struct {
+	ctl *control.Server
+
+	mu       sync.Mutex
+	settings config.MQTT
+	client   paho.Client
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New returns a disconnected Service for ctl.
+func New(ctl *control.Server) *Service {
+	return &Service{ctl: ctl}
+}
+
+// Apply connects, disconnects or reconnects to match the settings. The
+// connection is retried in the background, the app works without the broker.
+// Apply does nothing on a nil Service.
+func (s *Service) Apply(settings config.MQTT) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil && settings == s.settings {
+		return
+	}
+	if s.client != nil {
+		close(s.stop)
+		<-s.done
+		// A clean disconnect does not send the will, report it ourselves.
+		s.client.Publish(availabilityTopic(s.settings), 1, true, "offline").WaitTimeout(time.Second)
+		s.client.Disconnect(250)
+		s.client = nil
+		slog.Info("MQTT disconnected", "broker", s.settings.Broker)
+	}
+	s.settings = settings
+	if settings.Broker == "" {
+		return
+	}
+
+	id := clientID(settings)
+	opts := paho.NewClientOptions().
+		AddBroker(settings.Broker).
+		SetClientID(id).
+		SetUsername(settings.Username).
+		SetPassword(settings.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10*time.Second).
+		SetMaxReconnectInterval(5*time.Minute).
+		SetWill(availabilityTopic(settings), "offline", 1, true)
+
+	publish := make(chan struct{}, 1)
+	opts.SetOnConnectHandler(func(c paho.Client) {
+		slog.Info("MQTT connected", "broker", settings.Broker)
+		c.Publish(availabilityTopic(settings), 1, true, "online")
+		for _, m := range discovery(settings, id) {
+			c.Publish(m.topic, 1, true, m.payload)
+		}
+		c.Subscribe(settings.CommandTopic, 1, func(_ paho.Client, msg paho.Message) {
+			s.command(msg.Payload())
+			select {
+			case publish <- struct{}{}:
+			default:
+			}
+		})
+		select {
+		case publish <- struct{}{}:
+		default:
+		}
+	})
+	opts.SetConnectionLostHandler(func(_ paho.Client, err error) {
+		slog.Warn("MQTT connection lost", "broker", settings.Broker, "err", err)
+	})
+
+	s.client = paho.NewClient(opts)
+	s.client.Connect()
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.publishLoop(s.client, settings.StateTopic, publish, s.stop, s.done)
+}
+
+// publishLoop publishes the status on every change, on request and every
+// publishEvery, skipping repeats of the last payload between ticks.
+func (s *Service) publishLoop(c paho.Client, topic string, publish <-chan struct{}, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	changes, cancel := s.ctl.Subscribe()
+	defer cancel()
+	ticker := time.NewTicker(publishEvery)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		force := false
+		select {
+		case <-changes:
+		case <-publish:
+			force = true
+		case <-ticker.C:
+			force = true
+		case <-stop:
+			return
+		}
+		if !c.IsConnectionOpen() {
+			continue
+		}
+		payload, err := json.Marshal(s.ctl.Status())
+		if err != nil {
+			continue
+		}
+		if !force && string(payload) == string(last) {
+			continue
+		}
+		c.Publish(topic, 0, true, payload)
+		last = payload
+	}
+}
+
+// command runs a command received on the command topic.
+func (s *Service) command(payload []byte) {
+	req, err := parseCommand(payload)
+	if err != nil {
+		slog.Warn("Ignoring MQTT command", "payload", string(payload), "err", err)
+		return
+	}
+	if resp := s.ctl.Do(req); !resp.OK {
+		slog.Warn("MQTT command failed", "command", req.Command, "err", resp.Error)
+	}
+}
+
+// parseCommand reads "reset", "snooze 10" or {"command": "snooze", "minutes": 10}.
+func parseCommand(payload []byte) (control.Request, error) {
+	var req control.Request
+	text := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(text, "{") {
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			return req, err
+		}
+	} else {
+		fields := strings.Fields(text)
+		if len(fields) == 0 || len(fields) > 2 {
+			return req, fmt.Errorf("want a command and optional minutes")
+		}
+		req.Command = strings.ToLower(fields[0])
+		if len(fields) == 2 {
+			minutes, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return req, fmt.Errorf("invalid minutes %q", fields[1])
+			}
+			req.Minutes = minutes
+		}
+	}
+	if req.Minutes < 0 || (req.Minutes > 0 && req.Command != "snooze") {
+		return req, fmt.Errorf("minutes only apply to snooze")
+	}
+	return req, nil
+}
+
+// availabilityTopic is where "online" and "offline" are published, retained.
+func availabilityTopic(settings config.MQTT) string {
+	return settings.StateTopic + "/availability"
+}
+
+// clientID is the configured client ID or one made from the host name, so
+// every computer is its own device in Home Assistant.
+func clientID(settings config.MQTT) string {
+	if settings.ClientID != "" {
+		return settings.ClientID
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "hydra-reminder"
+	}
+	return "hydra-reminder-" + strings.ToLower(host)
+}
+
+// message is a retained message to publish.
+type message struct {
+	topic   string
+	payload []byte
+}
+
+var nodeIDUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// discovery returns the Home Assistant discovery configs for the device, none
+// if discovery is off.
+func discovery(settings config.MQTT, id string) []message {
+	if settings.DiscoveryPrefix == "" {
+		return nil
+	}
+	node := nodeIDUnsafe.ReplaceAllString(id, "_")
+	device := map[string]any{
+		"identifiers":  []string{node},
+		"name":         "HydraReminder",
+		"model":        "hydra-reminder",
+		"manufacturer": "HydraReminder",
+	}
+	entity := func(component, object string, fields map[string]any) message {
+		cfg := map[string]any{
+			"unique_id":          node + "_" + object,
+			"object_id":          node + "_" + object,
+			"availability_topic": availabilityTopic(settings),
+			"device":             device,
+		}
+		for k, v := range fields {
+			cfg[k] = v
+		}
+		payload, _ := json.Marshal(cfg)
+		return message{
+			topic:   fmt.Sprintf("%s/%s/%s/%s/config", settings.DiscoveryPrefix, component, node, object),
+			payload: payload,
+		}
+	}
+
+	msgs := []message{
+		entity("sensor", "state", map[string]any{
+			"name":           "State",
+			"icon":           "mdi:cup-water",
+			"state_topic":    settings.StateTopic,
+			"value_template": "{{ value_json.state }}",
+		}),
+		entity("sensor", "remaining", map[string]any{
+			"name":                "Time left",
+			"icon":                "mdi:timer-sand",
+			"state_topic":         settings.StateTopic,
+			"value_template":      "{{ value_json.remaining_seconds }}",
+			"unit_of_measurement": "s",
+			"device_class":        "duration",
+		}),
+		entity("binary_sensor", "alert", map[string]any{
+			"name":           "Alert",
+			"icon":           "mdi:bell-ring",
+			"state_topic":    settings.StateTopic,
+			"value_template": "{{ 'ON' if value_json.state == 'alerting' else 'OFF' }}",
+		}),
+	}
+	for _, cmd := range []struct{ object, name, icon string }{
+		{"reset", "Reset", "mdi:restart"},
+		{"stop", "Stop", "mdi:stop"},
+		{"snooze", "Snooze", "mdi:sleep"},
+	} {
+		msgs = append(msgs, entity("button", cmd.object, map[string]any{
+			"name":          cmd.name,
+			"icon":          cmd.icon,
+			"command_topic": settings.CommandTopic,
+			"payload_press": cmd.object,
+		}))
+	}
+	return msgs
+}
@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/control"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    control.Request
+		wantErr bool
+	}{
+		{payload: "reset", want: control.Request{Command: "reset"}},
+		{payload: " Stop\n", want: control.Request{Command: "stop"}},
+		{payload: "snooze 10", want: control.Request{Command: "snooze", Minutes: 10}},
+		{payload: `{"command": "snooze", "minutes": 3}`, want: control.Request{Command: "snooze", Minutes: 3}},
+		{payload: "", wantErr: true},
+		{payload: "snooze ten", wantErr: true},
+		{payload: "reset 5", wantErr: true},
+		{payload: "snooze 1 2", wantErr: true},
+		{payload: `{"command":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCommand([]byte(tt.payload))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCommand(%q) error = %v, wantErr %v", tt.payload, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseCommand(%q) = %+v, want %+v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	settings := config.DefaultConfig().MQTT
+	msgs := discovery(settings, "hydra-reminder-desk.lan")
+	if len(msgs) != 6 {
+		t.Fatalf("got %d discovery messages, want 6", len(msgs))
+	}
+
+	byTopic := map[string]map[string]any{}
+	for _, m := range msgs {
+		var cfg map[string]any
+		if err := json.Unmarshal(m.payload, &cfg); err != nil {
+			t.Fatalf("%s: %v", m.topic, err)
+		}
+		byTopic[m.topic] = cfg
+	}
+
+	remaining := byTopic["homeassistant/sensor/hydra-reminder-desk_lan/remaining/config"]
+	if remaining == nil {
+		t.Fatalf("no remaining sensor in %v", byTopic)
+	}
+	if remaining["state_topic"] != settings.StateTopic || remaining["availability_topic"] != settings.StateTopic+"/availability" {
+		t.Errorf("remaining sensor topics = %v", remaining)
+	}
+	reset := byTopic["homeassistant/button/hydra-reminder-desk_lan/reset/config"]
+	if reset["command_topic"] != settings.CommandTopic || reset["payload_press"] != "reset" {
+		t.Errorf("reset button = %v", reset)
+	}
+	for topic, cfg := range byTopic {
+		if !strings.HasPrefix(cfg["unique_id"].(string), "hydra-reminder-desk_lan_") {
+			t.Errorf("%s: unique_id = %v", topic, cfg["unique_id"])
+		}
+	}
+
+	settings.DiscoveryPrefix = ""
+	if msgs := discovery(settings, "x"); len(msgs) != 0 {
+		t.Errorf("discovery off still announces %d entities", len(msgs))
+	}
+}
@@ -1,7 +1,7 @@
 package timer
 
 import (
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -12,56 +12,498 @@ const (
 	StateStopped State = iota
 	StateRunning
 	StateAlerting
+	StatePaused
+	// StateBreak follows an acknowledged alert while break tracking is on,
+	// until the user returns, see SetBreakTracking.
+	StateBreak
 )
 
+func (s State) String() string {
+	switch s {
+	case StateStopped:
+		return "stopped"
+	case StateRunning:
+		return "running"
+	case StateAlerting:
+		return "alerting"
+	case StatePaused:
+		return "paused"
+	case StateBreak:
+		return "break"
+	}
+	return "unknown"
+}
+
+// EventKind is a transition of the Manager, see Subscribe.
+type EventKind int
+
+const (
+	EventStart EventKind = iota
+	EventReset
+	EventSnooze
+	EventPause
+	EventResume
+	EventStop
+	EventAlert
+	EventAdjust
+	EventPhase  // a Pomodoro phase started by itself, see Pomodoro.Auto
+	EventBreak  // an acknowledged alert started a break
+	EventReturn // the user returned from a break, see Event.Break
+	EventHold   // an alert was held back by the AlertLimit, the next countdown started
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStart:
+		return "start"
+	case EventReset:
+		return "reset"
+	case EventSnooze:
+		return "snooze"
+	case EventPause:
+		return "pause"
+	case EventResume:
+		return "resume"
+	case EventStop:
+		return "stop"
+	case EventAlert:
+		return "alert"
+	case EventAdjust:
+		return "adjust"
+	case EventPhase:
+		return "phase"
+	case EventBreak:
+		return "break"
+	case EventReturn:
+		return "return"
+	case EventHold:
+		return "hold"
+	}
+	return "unknown"
+}
+
+// Phase is the part of a Pomodoro cycle the timer is in, PhaseNone outside
+// Pomodoro mode.
+type Phase int
+
+const (
+	PhaseNone Phase = iota
+	PhaseWork
+	PhaseShortBreak
+	PhaseLongBreak
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseWork:
+		return "work"
+	case PhaseShortBreak:
+		return "short_break"
+	case PhaseLongBreak:
+		return "long_break"
+	}
+	return ""
+}
+
+// Pomodoro configures Pomodoro mode, see Manager.SetPomodoro.
+type Pomodoro struct {
+	Work       time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	Cycles     int // work phases before a long break
+	// Auto starts the next phase when one ends. Otherwise the timer alerts and
+	// the next phase starts with Reset.
+	Auto bool
+}
+
+// AlertLimit caps how often alerts are delivered, see Manager.SetAlertLimit.
+type AlertLimit struct {
+	PerDay int // 0 for no cap
+	// MinGap merges an alert due sooner after the last delivered one into
+	// it. Alerts after a snooze are not merged, the user asked for them.
+	MinGap time.Duration
+}
+
+// Held reports the alerts the AlertLimit held back.
+type Held struct {
+	Merged int  // merged into the last delivered alert
+	Today  int  // held back by the daily cap
+	Capped bool // the daily cap is reached
+}
+
+// Event describes one transition.
+type Event struct {
+	Kind EventKind
+	From State // state before the transition
+	At   time.Time
+	// Countdown is the time left after the transition, 0 when stopped or alerting.
+	Countdown time.Duration
+	// Phase and Cycle are the Pomodoro phase after the transition.
+	Phase Phase
+	Cycle int
+	// Break is how long the break lasted, for EventReturn.
+	Break time.Duration
+	// Held is what the AlertLimit held back after the transition.
+	Held Held
+}
+
 type Manager struct {
 	mu        sync.Mutex
 	state     State
 	timer     *time.Timer
 	duration  time.Duration
+	countdown time.Duration // length of the current run, differs from duration after a snooze or pause
 	startTime time.Time
 	onStart   func()
 	onAlert   func()
 	onStop    func()
+	onPause   func()
+	onPhase   func()
+	observers []func(Event)
+
+	pomodoro *Pomodoro // nil outside Pomodoro mode
+	phase    Phase
+	cycle    int // 1 to pomodoro.Cycles, counting work phases
+
+	breaks     bool      // break tracking, see SetBreakTracking
+	breakStart time.Time // when the current break started
+	lastBreak  time.Duration
+
+	limit     AlertLimit
+	snoozed   bool      // the countdown is a snooze, exempt from MinGap
+	lastAlert time.Time // when the last alert was delivered
+	day       string    // the day dayAlerts counts, as 2006-01-02
+	dayAlerts int
+	held      Held
 }
 
-func NewManager(onStart func(), onAlert func(), onStop func()) *Manager {
+func NewManager(onStart func(), onAlert func(), onStop func(), onPause func()) *Manager {
 	return &Manager{
 		state:   StateStopped,
 		onStart: onStart,
 		onAlert: onAlert,
 		onStop:  onStop,
+		onPause: onPause,
 	}
 }
 
+// SetOnPhase sets fn to be called when Pomodoro mode's auto advance moves to
+// the next phase, after the start callback. With no alert to acknowledge it is
+// what tells the user that the phase is over.
+func (m *Manager) SetOnPhase(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPhase = fn
+}
+
 func (m *Manager) Start(d time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.start(d, EventStart)
+}
 
+// Internal func, assumes lock is held
+func (m *Manager) start(d time.Duration, kind EventKind) {
+	from := m.state
 	m.duration = d
+	if m.pomodoro != nil {
+		// The phase decides, duration stays the interval for leaving Pomodoro mode.
+		d = m.phaseLength()
+	}
+	m.runInternal(d)
+	m.snoozed = false
+
+	if m.pomodoro != nil {
+		slog.Info("Pomodoro phase started", "phase", m.phase, "cycle", m.cycle, "duration", d)
+	} else {
+		slog.Info("Timer started", "duration", d)
+	}
+	m.emit(kind, from)
+	// A held back alert is not a start the user asked for, observers of
+	// EventHold can still update what they show.
+	if m.onStart != nil && kind != EventHold {
+		m.onStart()
+	}
+}
+
+// Subscribe adds fn to the observers of every transition. Observers are
+// called synchronously with the Manager's lock held, so they must be quick and
+// must not call back into the Manager.
+func (m *Manager) Subscribe(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observers = append(m.observers, fn)
+}
+
+// Internal func, assumes lock is held
+func (m *Manager) emit(kind EventKind, from State) {
+	ev := Event{Kind: kind, From: from, At: time.Now(), Phase: m.phase, Cycle: m.cycle}
+	m.newDay(ev.At)
+	ev.Held = m.held
+	switch m.state {
+	case StateRunning:
+		ev.Countdown = m.remainingInternal()
+	case StatePaused:
+		ev.Countdown = m.countdown
+	}
+	if kind == EventReturn {
+		ev.Break = m.lastBreak
+	}
+	for _, fn := range m.observers {
+		fn(ev)
+	}
+}
+
+// SetPomodoro switches Pomodoro mode on with the given settings, or off with
+// nil. Switching the mode restarts a running or alerting timer, a stopped or
+// paused one starts in the new mode with its next Start or Reset. Changing
+// the settings while in Pomodoro mode applies from the next phase on.
+func (m *Manager) SetPomodoro(p *Pomodoro) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switched := (p == nil) != (m.pomodoro == nil)
+	if p != nil {
+		copied := *p
+		copied.Cycles = max(copied.Cycles, 1)
+		m.pomodoro = &copied
+		if m.phase == PhaseNone {
+			m.phase, m.cycle = PhaseWork, 1
+		}
+		m.cycle = min(m.cycle, m.pomodoro.Cycles)
+	} else {
+		m.pomodoro = nil
+		m.phase, m.cycle = PhaseNone, 0
+	}
+
+	if switched && m.duration > 0 && (m.state == StateRunning || m.state == StateAlerting) {
+		m.start(m.duration, EventStart)
+	}
+}
+
+// Phase returns the Pomodoro phase and cycle, PhaseNone and 0 outside
+// Pomodoro mode.
+func (m *Manager) Phase() (Phase, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.phase, m.cycle
+}
+
+// Internal func, assumes lock is held
+func (m *Manager) phaseLength() time.Duration {
+	switch m.phase {
+	case PhaseShortBreak:
+		return m.pomodoro.ShortBreak
+	case PhaseLongBreak:
+		return m.pomodoro.LongBreak
+	}
+	return m.pomodoro.Work
+}
+
+// advance moves to the phase after the current one: a break after work, the
+// long one after the last cycle, and work after a break.
+// Internal func, assumes lock is held
+func (m *Manager) advance() {
+	switch m.phase {
+	case PhaseWork:
+		if m.cycle >= m.pomodoro.Cycles {
+			m.phase = PhaseLongBreak
+		} else {
+			m.phase = PhaseShortBreak
+		}
+	case PhaseShortBreak:
+		m.phase = PhaseWork
+		m.cycle++
+	default:
+		m.phase, m.cycle = PhaseWork, 1
+	}
+}
+
+// SetBreakTracking turns break tracking on or off. With it on, acknowledging
+// an alert with Reset starts a break instead of the next countdown, which
+// starts when the user returns with Reset or EndBreak. Pomodoro mode has
+// breaks of its own and ignores it. Turning it off during a break ends the
+// break.
+func (m *Manager) SetBreakTracking(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.breaks = on
+	if !on && m.state == StateBreak {
+		m.endBreak()
+	}
+}
+
+// EndBreak starts the next countdown if the timer is on a break, and does
+// nothing otherwise.
+func (m *Manager) EndBreak() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == StateBreak {
+		m.endBreak()
+	}
+}
+
+// BreakElapsed returns how long the current break has lasted so far, 0 when
+// not on a break.
+func (m *Manager) BreakElapsed() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state != StateBreak {
+		return 0
+	}
+	return time.Since(m.breakStart)
+}
+
+// Internal func, assumes lock is held
+func (m *Manager) startBreak() {
+	from := m.state
 	m.stopInternal()
+	m.state = StateBreak
+	m.breakStart = time.Now()
 
-	m.state = StateRunning
-	m.startTime = time.Now()
+	slog.Info("Break started")
+	m.emit(EventBreak, from)
+}
 
-	// Create a new timer
-	m.timer = time.AfterFunc(m.duration, func() {
-		m.triggerAlert()
-	})
+// Internal func, assumes lock is held
+func (m *Manager) endBreak() {
+	m.lastBreak = time.Since(m.breakStart)
+	slog.Info("Back from break", "after", m.lastBreak.Round(time.Second))
+	m.start(m.duration, EventReturn)
+}
 
-	log.Printf("Timer started for %v", m.duration)
+// SetDuration changes the duration used by the next Reset or Toggle without
+// touching the current countdown.
+func (m *Manager) SetDuration(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duration = d
+}
+
+// Snooze postpones the next alert by d without changing the configured duration,
+// so the following Reset starts a full interval again.
+func (m *Manager) Snooze(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.duration == 0 {
+		return
+	}
+	from := m.state
+	m.runInternal(d)
+	m.snoozed = true
+
+	slog.Info("Timer snoozed", "for", d)
+	m.emit(EventSnooze, from)
 	if m.onStart != nil {
 		m.onStart()
 	}
 }
 
+// Pause freezes a running countdown. Resume continues it with the time that was left.
+func (m *Manager) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state != StateRunning {
+		return
+	}
+	m.countdown = m.remainingInternal()
+	m.stopInternal()
+	m.state = StatePaused
+
+	slog.Info("Timer paused", "left", m.countdown.Round(time.Second))
+	m.emit(EventPause, StateRunning)
+	if m.onPause != nil {
+		m.onPause()
+	}
+}
+
+func (m *Manager) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state != StatePaused {
+		return
+	}
+	m.runInternal(m.countdown)
+
+	slog.Info("Timer resumed", "left", m.countdown.Round(time.Second))
+	m.emit(EventResume, StatePaused)
+	if m.onStart != nil {
+		m.onStart()
+	}
+}
+
+// TogglePause pauses a running timer or resumes a paused one.
+func (m *Manager) TogglePause() {
+	if m.GetState() == StatePaused {
+		m.Resume()
+	} else {
+		m.Pause()
+	}
+}
+
+// Adjust adds d, which may be negative, to the time left of a running or paused
+// countdown. Shortening stops at a minute left, or at what was left if that was
+// less, so it never raises the alert by itself.
+func (m *Manager) Adjust(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var left time.Duration
+	switch m.state {
+	case StateRunning:
+		left = m.remainingInternal()
+	case StatePaused:
+		left = m.countdown
+	default:
+		return
+	}
+	adjusted := max(left+d, min(left, time.Minute))
+
+	if m.state == StateRunning {
+		m.runInternal(adjusted)
+	} else {
+		m.countdown = adjusted
+	}
+	slog.Info("Timer adjusted", "by", d, "left", adjusted.Round(time.Second))
+	m.emit(EventAdjust, m.state)
+}
+
 func (m *Manager) triggerAlert() {
 	m.mu.Lock()
 	if m.state != StateRunning {
 		m.mu.Unlock()
 		return
 	}
+	if m.pomodoro != nil && m.pomodoro.Auto {
+		m.advance()
+		m.start(m.duration, EventPhase)
+		onPhase := m.onPhase
+		m.mu.Unlock()
+		if onPhase != nil {
+			onPhase()
+		}
+		return
+	}
+	if m.holdBack(time.Now()) {
+		// As if the alert had been acknowledged right away.
+		if m.pomodoro != nil {
+			m.advance()
+		}
+		m.start(m.duration, EventHold)
+		m.mu.Unlock()
+		return
+	}
+	m.lastAlert = time.Now()
+	m.dayAlerts++
+	m.held.Merged = 0
 	m.state = StateAlerting
+	m.emit(EventAlert, StateRunning)
 	m.mu.Unlock()
 
 	if m.onAlert != nil {
@@ -69,30 +511,100 @@ func (m *Manager) triggerAlert() {
 	}
 }
 
+// SetAlertLimit sets the cap on alerts, the zero AlertLimit delivers all.
+func (m *Manager) SetAlertLimit(l AlertLimit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = l
+}
+
+// RestoreAlerts sets the alerts delivered today and when the last one was,
+// for the AlertLimit to count alerts from before a restart.
+func (m *Manager) RestoreAlerts(today int, last time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.day = time.Now().Format(time.DateOnly)
+	m.dayAlerts = today
+	m.lastAlert = last
+}
+
+// Held returns the alerts the AlertLimit held back.
+func (m *Manager) Held() Held {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.newDay(time.Now())
+	return m.held
+}
+
+// Internal func, assumes lock is held
+func (m *Manager) newDay(now time.Time) {
+	if day := now.Format(time.DateOnly); day != m.day {
+		m.day, m.dayAlerts = day, 0
+		m.held.Today, m.held.Capped = 0, false
+	}
+}
+
+// holdBack decides whether the alert due now is held back, and counts it.
+// Internal func, assumes lock is held
+func (m *Manager) holdBack(now time.Time) bool {
+	m.newDay(now)
+	if m.limit.PerDay > 0 && m.dayAlerts >= m.limit.PerDay {
+		if !m.held.Capped {
+			slog.Info("Daily alert limit reached", "limit", m.limit.PerDay)
+		}
+		m.held.Capped = true
+		m.held.Today++
+		return true
+	}
+	if since := now.Sub(m.lastAlert); m.limit.MinGap > 0 && !m.snoozed && since < m.limit.MinGap {
+		slog.Info("Alert merged into the previous one", "since", since.Round(time.Second))
+		m.held.Merged++
+		return true
+	}
+	return false
+}
+
 func (m *Manager) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	from := m.state
 	m.stopInternal()
 	m.state = StateStopped
+	if m.pomodoro != nil {
+		// The next start begins a fresh set of cycles.
+		m.phase, m.cycle = PhaseWork, 1
+	}
 
+	m.emit(EventStop, from)
 	if m.onStop != nil {
 		m.onStop()
 	}
 }
 
 // Reset will restart the timer with the current duration, turning off any alert state.
+// In Pomodoro mode it restarts the current phase, or starts the next one while
+// alerting. With break tracking on it acknowledges an alert by starting a
+// break, and ends a break.
 func (m *Manager) Reset() {
 	m.mu.Lock()
-	d := m.duration
+	defer m.mu.Unlock()
+
 	// Prevent resetting if we haven't ever set a duration
-	if d == 0 {
-		m.mu.Unlock()
+	if m.duration == 0 {
 		return
 	}
-	m.mu.Unlock()
-
-	m.Start(d)
+	switch {
+	case m.state == StateBreak:
+		m.endBreak()
+		return
+	case m.pomodoro != nil && m.state == StateAlerting:
+		m.advance()
+	case m.breaks && m.state == StateAlerting:
+		m.startBreak()
+		return
+	}
+	m.start(m.duration, EventReset)
 }
 
 func (m *Manager) Toggle() {
@@ -103,6 +615,10 @@ func (m *Manager) Toggle() {
 
 	if state == StateRunning || state == StateAlerting {
 		m.Stop()
+	} else if state == StateBreak {
+		m.EndBreak()
+	} else if state == StatePaused {
+		m.Resume()
 	} else if d > 0 {
 		m.Start(d)
 	}
@@ -119,16 +635,38 @@ func (m *Manager) GetState() State {
 func (m *Manager) TimeRemaining() time.Duration {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.state != StateRunning {
-		return 0
+	switch m.state {
+	case StateRunning:
+		return m.remainingInternal()
+	case StatePaused:
+		return m.countdown
 	}
-	remaining := m.duration - time.Since(m.startTime)
+	return 0
+}
+
+// Internal func, assumes lock is held
+func (m *Manager) remainingInternal() time.Duration {
+	remaining := m.countdown - time.Since(m.startTime)
 	if remaining < 0 {
 		return 0
 	}
 	return remaining
 }
 
+// Internal func, assumes lock is held
+func (m *Manager) runInternal(d time.Duration) {
+	m.stopInternal()
+
+	m.state = StateRunning
+	m.countdown = d
+	m.startTime = time.Now()
+
+	// Create a new timer
+	m.timer = time.AfterFunc(d, func() {
+		m.triggerAlert()
+	})
+}
+
 // Internal func, assumes lock is held
 func (m *Manager) stopInternal() {
 	if m.timer != nil {
@@ -0,0 +1,222 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeReportsTransitions(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil)
+	var got []Event
+	m.Subscribe(func(ev Event) { got = append(got, ev) })
+
+	m.Start(time.Minute)
+	m.Pause()
+	m.Resume()
+	m.Reset()
+	m.Snooze(5 * time.Minute)
+	m.Stop()
+
+	want := []struct {
+		kind EventKind
+		from State
+	}{
+		{EventStart, StateStopped},
+		{EventPause, StateRunning},
+		{EventResume, StatePaused},
+		{EventReset, StateRunning},
+		{EventSnooze, StateRunning},
+		{EventStop, StateRunning},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Kind != w.kind || got[i].From != w.from {
+			t.Errorf("event %d = %v from %v, want %v from %v", i, got[i].Kind, got[i].From, w.kind, w.from)
+		}
+	}
+	if c := got[4].Countdown; c <= 4*time.Minute || c > 5*time.Minute {
+		t.Errorf("snooze countdown = %v, want about 5m", c)
+	}
+}
+
+func TestPomodoroCycles(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil)
+	m.SetPomodoro(&Pomodoro{Work: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Cycles: 2})
+	m.Start(time.Hour)
+
+	want := []struct {
+		phase Phase
+		cycle int
+		left  time.Duration
+	}{
+		{PhaseWork, 1, 25 * time.Minute},
+		{PhaseShortBreak, 1, 5 * time.Minute},
+		{PhaseWork, 2, 25 * time.Minute},
+		{PhaseLongBreak, 2, 15 * time.Minute},
+		{PhaseWork, 1, 25 * time.Minute},
+	}
+	for i, w := range want {
+		if i > 0 {
+			m.triggerAlert()
+			if s := m.GetState(); s != StateAlerting {
+				t.Fatalf("step %d: state %v after the phase ended, want alerting", i, s)
+			}
+			m.Reset()
+		}
+		phase, cycle := m.Phase()
+		if phase != w.phase || cycle != w.cycle {
+			t.Errorf("step %d: phase %v %d, want %v %d", i, phase, cycle, w.phase, w.cycle)
+		}
+		if left := m.TimeRemaining(); left > w.left || left < w.left-time.Second {
+			t.Errorf("step %d: %v left, want %v", i, left, w.left)
+		}
+	}
+
+	// Reset while running restarts the phase instead of skipping it.
+	m.Reset()
+	if phase, _ := m.Phase(); phase != PhaseWork {
+		t.Errorf("reset while running moved to %v", phase)
+	}
+	m.Stop()
+}
+
+func TestPomodoroAutoAdvance(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil)
+	var got []Event
+	m.Subscribe(func(ev Event) { got = append(got, ev) })
+	notified := 0
+	m.SetOnPhase(func() { notified++ })
+	m.SetPomodoro(&Pomodoro{Work: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Cycles: 4, Auto: true})
+	m.Start(time.Hour)
+	m.triggerAlert()
+
+	if s := m.GetState(); s != StateRunning {
+		t.Errorf("state %v, want running into the break", s)
+	}
+	if notified != 1 {
+		t.Errorf("phase callback called %d times, want once", notified)
+	}
+	last := got[len(got)-1]
+	if last.Kind != EventPhase || last.Phase != PhaseShortBreak || last.Cycle != 1 {
+		t.Errorf("last event = %+v, want a phase event for short break 1", last)
+	}
+
+	m.Stop()
+	m.Start(time.Hour)
+	if phase, cycle := m.Phase(); phase != PhaseWork || cycle != 1 {
+		t.Errorf("after stop and start: %v %d, want work 1", phase, cycle)
+	}
+}
+
+func TestPomodoroSwitchOff(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil)
+	m.SetPomodoro(&Pomodoro{Work: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Cycles: 4})
+	m.Start(time.Hour)
+	m.SetPomodoro(nil)
+
+	if phase, _ := m.Phase(); phase != PhaseNone {
+		t.Errorf("phase %v after leaving Pomodoro mode", phase)
+	}
+	if left := m.TimeRemaining(); left < time.Hour-time.Second {
+		t.Errorf("%v left, want the interval restarted", left)
+	}
+	m.Stop()
+}
+
+func TestBreakTracking(t *testing.T) {
+	m := NewManager(nil, nil, nil, nil)
+	var got []Event
+	m.Subscribe(func(ev Event) { got = append(got, ev) })
+	m.SetBreakTracking(true)
+	m.Start(time.Hour)
+	m.triggerAlert()
+	m.Reset()
+
+	if s := m.GetState(); s != StateBreak {
+		t.Fatalf("state %v after acknowledging, want break", s)
+	}
+	if left := m.TimeRemaining(); left != 0 {
+		t.Errorf("%v left during the break, want 0", left)
+	}
+	m.breakStart = m.breakStart.Add(-3 * time.Minute)
+	m.Reset()
+
+	if s := m.GetState(); s != StateRunning {
+		t.Fatalf("state %v after returning, want running", s)
+	}
+	last := got[len(got)-1]
+	if last.Kind != EventReturn || last.From != StateBreak || last.Break < 3*time.Minute || last.Break > 3*time.Minute+time.Second {
+		t.Errorf("last event = %+v, want a return after a 3m break", last)
+	}
+
+	// Turning it off ends a break and lets Reset acknowledge as before.
+	m.triggerAlert()
+	m.Reset()
+	m.SetBreakTracking(false)
+	if s := m.GetState(); s != StateRunning {
+		t.Errorf("state %v after turning break tracking off, want running", s)
+	}
+	m.triggerAlert()
+	m.Reset()
+	if s := m.GetState(); s != StateRunning {
+		t.Errorf("state %v after reset without break tracking, want running", s)
+	}
+	m.Stop()
+}
+
+func TestAlertLimit(t *testing.T) {
+	starts := 0
+	m := NewManager(func() { starts++ }, nil, nil, nil)
+	var got []Event
+	m.Subscribe(func(ev Event) { got = append(got, ev) })
+	m.SetAlertLimit(AlertLimit{PerDay: 2, MinGap: 10 * time.Minute})
+	m.Start(time.Hour)
+
+	m.triggerAlert()
+	if s := m.GetState(); s != StateAlerting {
+		t.Fatalf("state %v after the first alert, want alerting", s)
+	}
+	m.Reset()
+	m.triggerAlert()
+	if s := m.GetState(); s != StateRunning {
+		t.Fatalf("state %v for an alert within the gap, want running", s)
+	}
+	if last := got[len(got)-1]; last.Kind != EventHold || last.Held.Merged != 1 {
+		t.Errorf("last event = %v with %+v, want hold with one merged alert", last.Kind, last.Held)
+	}
+	if h := m.Held(); h.Merged != 1 || h.Capped {
+		t.Errorf("Held() = %+v, want one merged alert", h)
+	}
+	if starts != 2 {
+		t.Errorf("start callback called %d times, want no call for the held alert", starts)
+	}
+
+	// A snooze asked for its alert, the gap does not apply.
+	m.Snooze(time.Minute)
+	m.triggerAlert()
+	if s := m.GetState(); s != StateAlerting {
+		t.Fatalf("state %v after a snooze, want alerting", s)
+	}
+	if h := m.Held(); h.Merged != 0 {
+		t.Errorf("Held() = %+v after a delivered alert, want the merged count reset", h)
+	}
+
+	// Two alerts delivered today, the cap holds back the third.
+	m.Reset()
+	m.lastAlert = m.lastAlert.Add(-time.Hour)
+	m.triggerAlert()
+	if h := m.Held(); m.GetState() != StateRunning || !h.Capped || h.Today != 1 {
+		t.Errorf("state %v, Held() = %+v, want the alert held back by the cap", m.GetState(), h)
+	}
+
+	// A new day starts counting again.
+	m.RestoreAlerts(0, time.Time{})
+	m.day = "2000-01-01"
+	m.triggerAlert()
+	if s := m.GetState(); s != StateAlerting {
+		t.Errorf("state %v on a new day, want alerting", s)
+	}
+	m.Stop()
+}
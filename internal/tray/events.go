@@ -0,0 +1,93 @@
+package tray
+
+import (
+	"time"
+
+	"hydra-reminder/internal/timer"
+)
+
+// trayEventKind is how the user interacted with the tray icon itself, as
+// opposed to clicking one of its menu items.
+type trayEventKind int
+
+const (
+	// eventActivate is a left click.
+	eventActivate trayEventKind = iota
+	// eventSecondaryActivate is a middle click.
+	eventSecondaryActivate
+	// eventMenu is a right click or the menu being opened.
+	eventMenu
+	// eventScroll is the mouse wheel over the icon.
+	eventScroll
+)
+
+func (k trayEventKind) String() string {
+	switch k {
+	case eventActivate:
+		return "activate"
+	case eventSecondaryActivate:
+		return "secondary-activate"
+	case eventMenu:
+		return "menu"
+	case eventScroll:
+		return "scroll"
+	}
+	return "unknown"
+}
+
+type trayEvent struct {
+	kind trayEventKind
+	// delta is the number of scroll steps for eventScroll, positive is up.
+	delta int
+}
+
+// onTrayEvent is called by the platform's event source and runs the action the
+// config maps the interaction to.
+func (t *TrayApp) onTrayEvent(ev trayEvent) {
+	cfg := t.live.Load()
+	var action string
+	switch ev.kind {
+	case eventActivate:
+		action = cfg.TrayLeftClick
+	case eventSecondaryActivate:
+		action = cfg.TrayMiddleClick
+	case eventMenu:
+		action = cfg.TrayMenuOpen
+	case eventScroll:
+		if ev.delta > 0 {
+			action = cfg.TrayScrollUp
+		} else {
+			action = cfg.TrayScrollDown
+		}
+	}
+	t.runTrayAction(action)
+}
+
+// runTrayAction performs one of config.TrayActions.
+func (t *TrayApp) runTrayAction(action string) {
+	if t.timerManager == nil {
+		return
+	}
+	cfg := t.live.Load()
+	step := time.Duration(cfg.ScrollStepMinutes) * time.Minute
+	switch action {
+	case "acknowledge":
+		// Ends the alert, or the break that acknowledging it started.
+		if state := t.timerManager.GetState(); state == timer.StateAlerting || state == timer.StateBreak {
+			t.timerManager.Reset()
+		}
+	case "reset":
+		t.timerManager.Reset()
+	case "pause":
+		t.timerManager.TogglePause()
+	case "snooze":
+		t.timerManager.Snooze(time.Duration(cfg.SnoozeMinutes) * time.Minute)
+	case "drink":
+		t.logDrink()
+		t.timerManager.Reset()
+	case "add_time":
+		t.timerManager.Adjust(step)
+	case "subtract_time":
+		t.timerManager.Adjust(-step)
+	}
+}
@@ -2,16 +2,22 @@ package tray
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/getlantern/systray"
 
+	"hydra-reminder/internal/adaptive"
 	"hydra-reminder/internal/autostart"
+	"hydra-reminder/internal/breaks"
 	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/history"
 	"hydra-reminder/internal/hotkey"
+	"hydra-reminder/internal/logging"
 	"hydra-reminder/internal/timer"
 )
 
@@ -19,10 +25,18 @@ var (
 	IconStopped []byte
 	IconRunning []byte
 	IconAlert   []byte
+
+	// Pomodoro phases, IconRunning is used while these are unset.
+	IconWork       []byte
+	IconShortBreak []byte
+	IconLongBreak  []byte
 )
 
 type TrayApp struct {
+	// cfg is only used on the event loop in onReady, which publishes each
+	// change to live for the goroutines that update the icon and menu.
 	cfg          *config.Config
+	live         *config.Live
 	timerManager *timer.Manager
 	blinkTicker  *time.Ticker
 	blinkDone    chan struct{}
@@ -30,19 +44,149 @@ type TrayApp struct {
 	iconIsAlert  bool
 	timeTicker   *time.Ticker
 	timeItem     *systray.MenuItem
+	breakItem    *systray.MenuItem // warns about short breaks, hidden otherwise
+	adaptive     adaptiveItems
+	menu         menuItems
+	reloadCh     chan *config.Config
+	profileCh    chan string
+	resetKeyCh   chan uint32
+	durationCh   chan int
+	overrides    *config.Overrides
+
+	// viewMu guards the timer's phase and held alerts as of its last event,
+	// see Observe.
+	viewMu sync.Mutex
+	phase  timer.Phase
+	cycle  int
+	held   timer.Held
+
+	autostartBroken bool
+
+	configProblems []string
+
+	breaks  *breaks.Tracker
+	advisor *adaptive.Advisor
+	history *history.Log // nil without a history
+
+	atExit []func()
+}
+
+// adaptiveItems explain the adaptive interval's suggestion, hidden while
+// adaptive mode is off.
+type adaptiveItems struct {
+	menu    *systray.MenuItem
+	summary *systray.MenuItem
+	reason  *systray.MenuItem
+	apply   *systray.MenuItem // hidden unless there is a change to apply
 }
 
-func NewApp(cfg *config.Config) *TrayApp {
+// menuItems holds the checkable menu entries that mirror config values.
+type menuItems struct {
+	durations    []*systray.MenuItem // in the order of durationOptions
+	style        *systray.MenuItem
+	prefixes     []*systray.MenuItem // in the order of prefixOptions
+	hotkeyEnable *systray.MenuItem
+	chordEnable  *systray.MenuItem
+	resetKeys    []*systray.MenuItem // A-Z
+	autostart    *systray.MenuItem
+	profileMenu  *systray.MenuItem
+	profiles     map[string]*systray.MenuItem // by profile name, hidden once removed
+}
+
+var (
+	durationOptions = []int{0, 15, 30, 45, 60}
+	prefixOptions   = []uint32{0x0003, 0x0006, 0x000C}
+)
+
+// NewApp returns the tray for the config in live, which it publishes its
+// changes to.
+func NewApp(live *config.Live) *TrayApp {
 	return &TrayApp{
-		cfg:    cfg,
-		uiChan: make(chan func(), 10),
+		cfg:        live.Load().Clone(),
+		live:       live,
+		uiChan:     make(chan func(), 10),
+		reloadCh:   make(chan *config.Config, 1),
+		profileCh:  make(chan string, 1),
+		resetKeyCh: make(chan uint32, 1),
+		durationCh: make(chan int, 1),
 	}
 }
 
+// SetOverrides keeps session-only settings from flags and the environment out
+// of the saved config file.
+func (t *TrayApp) SetOverrides(o *config.Overrides) {
+	t.overrides = o
+}
+
+// ReloadConfig applies settings that were changed outside the app, e.g. by
+// editing config.json. It is safe to call from any goroutine.
+func (t *TrayApp) ReloadConfig(cfg *config.Config) {
+	t.reloadCh <- cfg
+}
+
 func (t *TrayApp) SetTimerManager(tm *timer.Manager) {
 	t.timerManager = tm
 }
 
+// Observe records the timer's phase and held alerts from its events. The timer
+// calls OnRunning, OnStop and OnPause with its lock held, so they show what
+// Observe recorded instead of asking the timer.
+func (t *TrayApp) Observe(ev timer.Event) {
+	t.viewMu.Lock()
+	defer t.viewMu.Unlock()
+	t.phase, t.cycle, t.held = ev.Phase, ev.Cycle, ev.Held
+}
+
+// timerPhase is the phase and cycle Observe recorded last.
+func (t *TrayApp) timerPhase() (timer.Phase, int) {
+	t.viewMu.Lock()
+	defer t.viewMu.Unlock()
+	return t.phase, t.cycle
+}
+
+// SetConfigProblems makes the menu show a warning listing what was wrong with
+// the config file when it was loaded.
+func (t *TrayApp) SetConfigProblems(problems []string) {
+	t.configProblems = problems
+}
+
+// SetBreakTracker makes the menu show tr's warning about short breaks.
+func (t *TrayApp) SetBreakTracker(tr *breaks.Tracker) {
+	t.breaks = tr
+}
+
+// SetHistory makes drinks logged from the tray go to hist.
+func (t *TrayApp) SetHistory(hist *history.Log) {
+	t.history = hist
+}
+
+// logDrink records a drink in the history.
+func (t *TrayApp) logDrink() {
+	slog.Info("Drink logged")
+	e := history.Entry{At: time.Now().UTC().Truncate(time.Second), Kind: history.KindDrink, Profile: t.live.Load().ActiveProfile}
+	if err := t.history.Append(e); err != nil {
+		slog.Error("Failed to record drink", "err", err)
+	}
+}
+
+// SetAdvisor makes the menu show a's suggested interval.
+func (t *TrayApp) SetAdvisor(a *adaptive.Advisor) {
+	t.advisor = a
+}
+
+// AtExit makes the app call f when it quits. The process exits right after,
+// so deferred calls in main do not run.
+func (t *TrayApp) AtExit(f func()) {
+	t.atExit = append(t.atExit, f)
+}
+
+// SetPhaseIcons sets the icons shown while a Pomodoro phase runs.
+func (t *TrayApp) SetPhaseIcons(work, shortBreak, longBreak []byte) {
+	IconWork = work
+	IconShortBreak = shortBreak
+	IconLongBreak = longBreak
+}
+
 func (t *TrayApp) Run(iconStopped, iconRunning, iconAlert []byte) {
 	IconStopped = iconStopped
 	IconRunning = iconRunning
@@ -51,9 +195,11 @@ func (t *TrayApp) Run(iconStopped, iconRunning, iconAlert []byte) {
 	systray.Run(t.onReady, t.onExit)
 }
 
+// saveConfig publishes and saves the config after a change from the menu.
 func (t *TrayApp) saveConfig() {
-	if err := config.Save(t.cfg); err != nil {
-		log.Printf("Failed to save config: %v", err)
+	t.live.Store(t.cfg)
+	if err := config.Save(t.overrides.ForSave(t.cfg)); err != nil {
+		slog.Error("Failed to save config", "err", err)
 	}
 }
 
@@ -62,6 +208,14 @@ func (t *TrayApp) onReady() {
 	systray.SetTitle("HydraReminder - Stopped")
 	systray.SetTooltip("HydraReminder - Stopped")
 
+	if len(t.configProblems) > 0 {
+		mProblems := systray.AddMenuItem("⚠ Config problems, defaults used", "The config file had invalid values")
+		for _, p := range t.configProblems {
+			mProblems.AddSubMenuItem(p, "").Disable()
+		}
+		systray.AddSeparator()
+	}
+
 	// Set up menus
 	mStartStop := systray.AddMenuItem("Start / Stop", "Toggle timer")
 	mReset := systray.AddMenuItem("Reset Timer", "Reset timer to current duration")
@@ -70,6 +224,9 @@ func (t *TrayApp) onReady() {
 
 	t.timeItem = systray.AddMenuItem("Time Remaining: --:--", "Time left before alert")
 	t.timeItem.Disable()
+	t.breakItem = systray.AddMenuItem("", "Set the minimum under \"breaks\" in config.json")
+	t.breakItem.Disable()
+	t.breakItem.Hide()
 
 	systray.AddSeparator()
 
@@ -80,6 +237,16 @@ func (t *TrayApp) onReady() {
 	mDir45 := mDuration.AddSubMenuItemCheckbox("45 min", "", t.cfg.DurationMinutes == 45)
 	mDir60 := mDuration.AddSubMenuItemCheckbox("60 min", "", t.cfg.DurationMinutes == 60)
 
+	mProfile := systray.AddMenuItem("Profile", "Switch between named sets of settings")
+
+	t.adaptive.menu = systray.AddMenuItem("Adaptive Interval", "An interval suggested from how you answered recent alerts")
+	t.adaptive.summary = t.adaptive.menu.AddSubMenuItem("", "")
+	t.adaptive.summary.Disable()
+	t.adaptive.reason = t.adaptive.menu.AddSubMenuItem("", "")
+	t.adaptive.reason.Disable()
+	t.adaptive.apply = t.adaptive.menu.AddSubMenuItem("Apply", "Switch to the suggested interval")
+	t.syncAdaptive()
+
 	systray.AddSeparator()
 
 	mStyle := systray.AddMenuItemCheckbox("Blink Mode", "Toggle icon blink on alert", t.cfg.AlertStyle == "blink")
@@ -94,6 +261,7 @@ func (t *TrayApp) onReady() {
 	mPrefSuperShift := mPrefixMenu.AddSubMenuItemCheckbox("SUPER + SHIFT", "", t.cfg.HotkeyModifiers == 0x000C)
 
 	mHotkeyEnable := mHotkeyMenu.AddSubMenuItemCheckbox("Enable Hotkey", "", t.cfg.HotkeyEnabled)
+	mChordEnable := mHotkeyMenu.AddSubMenuItemCheckbox("Enable Chord (Prefix + H, then R/S/D/P)", "Reset, snooze, log drink or pause", t.cfg.ChordEnabled)
 
 	mSetReset := mHotkeyMenu.AddSubMenuItem("Set Reset Key...", "")
 
@@ -106,24 +274,55 @@ func (t *TrayApp) onReady() {
 		resetItems = append(resetItems, ri)
 	}
 
-	enabled, _ := autostart.IsEnabled()
+	// Fix an entry left pointing at an old location before showing it.
+	status, err := autostart.Heal()
+	if err != nil {
+		slog.Warn("Failed to check autostart entry", "err", err)
+	}
+	enabled := status == autostart.StatusEnabled
 	// Update config to match reality in case registry differs from config
 	t.cfg.Autostart = enabled
 	t.saveConfig()
 
 	mAutostart := systray.AddMenuItemCheckbox("Enable Autostart", "Run on Windows startup", enabled)
+	t.autostartBroken = status == autostart.StatusBroken
+
+	t.menu = menuItems{
+		durations:    []*systray.MenuItem{mDir10s, mDir15, mDir30, mDir45, mDir60},
+		style:        mStyle,
+		prefixes:     []*systray.MenuItem{mPrefCtrlAlt, mPrefCtrlShift, mPrefSuperShift},
+		hotkeyEnable: mHotkeyEnable,
+		chordEnable:  mChordEnable,
+		resetKeys:    resetItems,
+		autostart:    mAutostart,
+		profileMenu:  mProfile,
+		profiles:     map[string]*systray.MenuItem{},
+	}
+	t.syncAutostartItem()
+	t.syncProfileItems()
 
 	systray.AddSeparator()
 
 	mHelp := systray.AddMenuItem("Help", "How to use HydraReminder")
 	mHelpState := mHelp.AddSubMenuItem("States: Grey=Stopped, Green=Running, Red=Alert", "")
 	mHelpState.Disable()
-	mHelpReset := mHelp.AddSubMenuItem("Reset: Click tray icon or use Reset Timer", "")
+	mHelpReset := mHelp.AddSubMenuItem("Reset: Click tray icon or use Reset Timer (see tray_left_click)", "")
 	mHelpReset.Disable()
 	mHelpBlink := mHelp.AddSubMenuItem("Blink Mode: Flashes icon red/green when alert triggers", "")
 	mHelpBlink.Disable()
 	mHelpHotkey := mHelp.AddSubMenuItem("Hotkeys: Start with CTRL+ALT+<Key> (configurable)", "")
 	mHelpHotkey.Disable()
+	mHelpChord := mHelp.AddSubMenuItem("Chord: Prefix+H, then R=Reset S=Snooze D=Drink P=Pause", "")
+	mHelpChord.Disable()
+	mHelpProfile := mHelp.AddSubMenuItem("Profiles: Switch settings sets, or run: hydra-reminder profile <name>", "")
+	mHelpProfile.Disable()
+	mHelpPomodoro := mHelp.AddSubMenuItem("Pomodoro: Work/break cycles, enable \"pomodoro\" in config.json", "")
+	mHelpPomodoro.Disable()
+	mHelpBreaks := mHelp.AddSubMenuItem("Breaks: Acknowledging starts a break, the timer restarts when you return", "")
+	mHelpBreaks.Disable()
+	mHelpAdaptive := mHelp.AddSubMenuItem("Adaptive Interval: Suggests an interval from your snoozes and delays, enable \"adaptive\"", "")
+	mHelpAdaptive.Disable()
+	mOpenLog := systray.AddMenuItem("Open Log", "Open the log file, e.g. to see why a hotkey or autostart failed")
 
 	systray.AddSeparator()
 	mQuit := systray.AddMenuItem("Quit", "Quit HydraReminder")
@@ -180,16 +379,45 @@ func (t *TrayApp) onReady() {
 					hotkey.Unregister()
 				}
 				t.saveConfig()
+			case <-mChordEnable.ClickedCh:
+				t.cfg.ChordEnabled = !t.cfg.ChordEnabled
+				if t.cfg.ChordEnabled {
+					mChordEnable.Check()
+					t.registerChord()
+				} else {
+					mChordEnable.Uncheck()
+					hotkey.UnregisterChord()
+				}
+				t.saveConfig()
 			case <-mAutostart.ClickedCh:
-				t.cfg.Autostart = !t.cfg.Autostart
+				// Clicking a broken entry repairs it.
+				t.cfg.Autostart = !t.cfg.Autostart || t.autostartBroken
+				var err error
 				if t.cfg.Autostart {
-					mAutostart.Check()
-					autostart.Enable()
+					err = autostart.Enable()
 				} else {
-					mAutostart.Uncheck()
-					autostart.Disable()
+					err = autostart.Disable()
 				}
+				if err != nil {
+					slog.Error("Failed to update autostart", "err", err)
+				}
+				t.autostartBroken = false
+				t.syncAutostartItem()
 				t.saveConfig()
+			case <-t.adaptive.apply.ClickedCh:
+				if s := t.advisor.Suggestion(); s.Change {
+					t.applyDuration(s.Minutes)
+				}
+			case <-mOpenLog.ClickedCh:
+				t.openLog()
+			case cfg := <-t.reloadCh:
+				t.applyConfig(cfg)
+			case name := <-t.profileCh:
+				t.switchProfile(name)
+			case key := <-t.resetKeyCh:
+				t.setResetKey(key)
+			case mins := <-t.durationCh:
+				t.applyDuration(mins)
 			case <-mQuit.ClickedCh:
 				systray.Quit()
 			}
@@ -211,19 +439,7 @@ func (t *TrayApp) onReady() {
 				lastHotkeyChange = time.Now()
 				hotkeyMu.Unlock()
 
-				t.cfg.HotkeyResetKey = uint32('A' + index)
-				t.saveConfig()
-
-				for _, other := range resetItems {
-					if other != mi {
-						other.Uncheck()
-					}
-				}
-				mi.Check()
-
-				if t.cfg.HotkeyEnabled {
-					hotkey.Register(t.cfg.HotkeyModifiers, t.cfg.HotkeyResetKey)
-				}
+				t.resetKeyCh <- uint32('A' + index)
 			}
 		}(i, item)
 	}
@@ -236,11 +452,23 @@ func (t *TrayApp) onReady() {
 				continue
 			}
 			state := t.timerManager.GetState()
+			t.syncBreakWarning()
+			t.syncAdaptive()
+			if label := t.phaseLabel(t.timerPhase()); label != "" && state != timer.StateStopped {
+				t.timeItem.SetTitle(phaseLine(label, state, t.timerManager.TimeRemaining()))
+				continue
+			}
 			switch state {
 			case timer.StateStopped:
 				t.timeItem.SetTitle("Time Remaining: Stopped")
 			case timer.StateAlerting:
 				t.timeItem.SetTitle("Time Remaining: 00:00 (Alert!)")
+			case timer.StateBreak:
+				d := t.timerManager.BreakElapsed()
+				t.timeItem.SetTitle(fmt.Sprintf("On a break: %02d:%02d", int(d.Minutes()), int(d.Seconds())%60))
+			case timer.StatePaused:
+				rem := t.timerManager.TimeRemaining()
+				t.timeItem.SetTitle(fmt.Sprintf("Time Remaining: %02d:%02d (Paused)", int(rem.Minutes()), int(rem.Seconds())%60))
 			default:
 				rem := t.timerManager.TimeRemaining()
 				mins := int(rem.Minutes())
@@ -250,34 +478,25 @@ func (t *TrayApp) onReady() {
 		}
 	}()
 
-	// Start polling to detect menu open for auto-reset
-	go t.monitorMenuOpen()
-
-	// Left click hook. In getlantern/systray, left-clicking icon emits an event on some channels,
-	// but currently it does not have a dedicated LeftClick action on Windows.
-	// Oh actually systray doesn't natively expose left vs right mouse click handling uniformly.
-	// That's fine, the standard menu actions are enough for a minimalist application.
+	// Watch for clicks on the icon and the menu opening, systray does not
+	// report those itself. See onTrayEvent.
+	go t.monitorTrayEvents()
 
 	// Register initial hotkeys
 	if t.cfg.HotkeyEnabled {
 		err := hotkey.Register(t.cfg.HotkeyModifiers, t.cfg.HotkeyResetKey)
 		if err != nil {
-			log.Printf("Failed to register hotkeys: %v", err)
+			slog.Error("Failed to register hotkeys", "err", err)
 		}
 	}
+	if t.cfg.ChordEnabled {
+		t.registerChord()
+	}
 
 	// Start timer immediately based on config, or leave stopped if previously stopped,
 	// but the project requirements said: "Start timer immediately based on config".
 	// Since we now have "Stopped" state, we should actually explicitly start it so it goes green.
-	var dur time.Duration
-	if t.cfg.DurationMinutes == 0 {
-		dur = 10 * time.Second
-	} else {
-		dur = time.Duration(t.cfg.DurationMinutes) * time.Minute
-	}
-
-	// Start it immediately
-	t.timerManager.Start(dur)
+	t.timerManager.Start(t.cfg.Duration())
 	// Make sure UI updates to running
 	t.OnRunning()
 }
@@ -292,8 +511,7 @@ func (t *TrayApp) handleDurationClick(lastChange *time.Time, mins int, items ...
 
 func (t *TrayApp) setDuration(mins int, items ...*systray.MenuItem) {
 	for i, item := range items {
-		opts := []int{0, 15, 30, 45, 60}
-		if opts[i] == mins {
+		if durationOptions[i] == mins {
 			item.Check()
 		} else {
 			item.Uncheck()
@@ -302,15 +520,44 @@ func (t *TrayApp) setDuration(mins int, items ...*systray.MenuItem) {
 	t.cfg.DurationMinutes = mins
 	t.saveConfig()
 
-	var dur time.Duration
-	if mins == 0 {
-		dur = 10 * time.Second
-	} else {
-		dur = time.Duration(mins) * time.Minute
+	t.timerManager.Start(t.cfg.Duration())
+	t.OnRunning()
+}
+
+// ApplyDuration switches to an interval of mins minutes and saves it, as the
+// adaptive interval does with its suggestion. A running countdown restarts
+// with the new interval. It is safe to call from any goroutine.
+func (t *TrayApp) ApplyDuration(mins int) {
+	t.durationCh <- mins
+}
+
+// applyDuration does ApplyDuration's work on the event loop.
+func (t *TrayApp) applyDuration(mins int) {
+	t.cfg.DurationMinutes = mins
+	t.saveConfig()
+	switch t.timerManager.GetState() {
+	case timer.StateRunning, timer.StateAlerting:
+		t.timerManager.Start(t.cfg.Duration())
+	default:
+		t.timerManager.SetDuration(t.cfg.Duration())
 	}
+	t.syncMenu()
+}
 
-	t.timerManager.Start(dur)
-	t.OnRunning()
+// setResetKey makes key the hotkey's reset key.
+func (t *TrayApp) setResetKey(key uint32) {
+	t.cfg.HotkeyResetKey = key
+	t.saveConfig()
+	for i, item := range t.menu.resetKeys {
+		if key == uint32('A'+i) {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+	if t.cfg.HotkeyEnabled {
+		hotkey.Register(t.cfg.HotkeyModifiers, t.cfg.HotkeyResetKey)
+	}
 }
 
 func (t *TrayApp) setHotkeyModifier(lastChange *time.Time, modifier uint32, items ...*systray.MenuItem) {
@@ -319,9 +566,8 @@ func (t *TrayApp) setHotkeyModifier(lastChange *time.Time, modifier uint32, item
 	}
 	*lastChange = time.Now()
 
-	opts := []uint32{0x0003, 0x0006, 0x000C}
 	for i, item := range items {
-		if opts[i] == modifier {
+		if prefixOptions[i] == modifier {
 			item.Check()
 		} else {
 			item.Uncheck()
@@ -333,24 +579,283 @@ func (t *TrayApp) setHotkeyModifier(lastChange *time.Time, modifier uint32, item
 	if t.cfg.HotkeyEnabled {
 		hotkey.Register(t.cfg.HotkeyModifiers, t.cfg.HotkeyResetKey)
 	}
+	if t.cfg.ChordEnabled {
+		t.registerChord()
+	}
+}
+
+// applyConfig switches to cfg, re-registering hotkeys, restarting the timer
+// and toggling autostart only where the values actually changed.
+func (t *TrayApp) applyConfig(cfg *config.Config) {
+	old := *t.cfg
+	*t.cfg = *cfg
+	t.live.Store(t.cfg)
+
+	if old.HotkeyEnabled != cfg.HotkeyEnabled || old.HotkeyModifiers != cfg.HotkeyModifiers ||
+		old.HotkeyResetKey != cfg.HotkeyResetKey {
+		if cfg.HotkeyEnabled {
+			if err := hotkey.Register(cfg.HotkeyModifiers, cfg.HotkeyResetKey); err != nil {
+				slog.Error("Failed to register hotkeys", "err", err)
+			}
+		} else {
+			hotkey.Unregister()
+		}
+	}
+
+	if old.ChordEnabled != cfg.ChordEnabled || old.HotkeyModifiers != cfg.HotkeyModifiers ||
+		old.ChordLeaderKey != cfg.ChordLeaderKey || old.ChordTimeoutMs != cfg.ChordTimeoutMs {
+		if cfg.ChordEnabled {
+			t.registerChord()
+		} else {
+			hotkey.UnregisterChord()
+		}
+	}
+
+	if old.AutostartBackend != cfg.AutostartBackend {
+		if err := autostart.SetBackend(cfg.AutostartBackend); err != nil {
+			slog.Warn("Using the default autostart backend", "err", err)
+		}
+	}
+	entryChanged := old.AutostartBackend != cfg.AutostartBackend ||
+		!slices.Equal(old.AutostartArgs, cfg.AutostartArgs) ||
+		old.AutostartDelaySeconds != cfg.AutostartDelaySeconds
+	if old.Autostart != cfg.Autostart || (cfg.Autostart && entryChanged) {
+		var err error
+		if cfg.Autostart {
+			err = autostart.Enable()
+		} else {
+			err = autostart.Disable()
+		}
+		if err != nil {
+			slog.Error("Failed to update autostart", "err", err)
+		}
+		t.autostartBroken = false
+	}
+
+	if old.DurationMinutes != cfg.DurationMinutes {
+		switch t.timerManager.GetState() {
+		case timer.StateRunning, timer.StateAlerting:
+			t.timerManager.Start(cfg.Duration())
+		default:
+			t.timerManager.SetDuration(cfg.Duration())
+		}
+	}
+
+	if old.AlertStyle != cfg.AlertStyle && t.timerManager.GetState() == timer.StateAlerting {
+		t.OnAlert()
+	}
+
+	t.syncMenu()
+}
+
+// syncMenu sets every checkmark from the current config.
+func (t *TrayApp) syncMenu() {
+	setChecked := func(item *systray.MenuItem, checked bool) {
+		if checked {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+
+	for i, item := range t.menu.durations {
+		setChecked(item, durationOptions[i] == t.cfg.DurationMinutes)
+	}
+	setChecked(t.menu.style, t.cfg.AlertStyle == "blink")
+	for i, item := range t.menu.prefixes {
+		setChecked(item, prefixOptions[i] == t.cfg.HotkeyModifiers)
+	}
+	setChecked(t.menu.hotkeyEnable, t.cfg.HotkeyEnabled)
+	setChecked(t.menu.chordEnable, t.cfg.ChordEnabled)
+	for i, item := range t.menu.resetKeys {
+		setChecked(item, t.cfg.HotkeyResetKey == uint32('A'+i))
+	}
+	t.syncAutostartItem()
+	t.syncProfileItems()
+}
+
+// syncAutostartItem shows a broken entry distinctly from a disabled one.
+func (t *TrayApp) syncAutostartItem() {
+	if t.autostartBroken {
+		t.menu.autostart.SetTitle("Autostart: Broken Entry (click to repair)")
+		t.menu.autostart.Uncheck()
+		return
+	}
+	t.menu.autostart.SetTitle("Enable Autostart")
+	if t.cfg.Autostart {
+		t.menu.autostart.Check()
+	} else {
+		t.menu.autostart.Uncheck()
+	}
+}
+
+// syncProfileItems adds menu entries for new profiles, hides removed ones and
+// checks the active one.
+func (t *TrayApp) syncProfileItems() {
+	if t.cfg.ActiveProfile != "" {
+		t.menu.profileMenu.SetTitle("Profile: " + t.cfg.ActiveProfile)
+	} else {
+		t.menu.profileMenu.SetTitle("Profile")
+	}
+
+	for _, name := range t.cfg.ProfileNames() {
+		item, ok := t.menu.profiles[name]
+		if !ok {
+			item = t.menu.profileMenu.AddSubMenuItemCheckbox(name, "Switch to profile "+name, false)
+			t.menu.profiles[name] = item
+			go func(name string, mi *systray.MenuItem) {
+				for range mi.ClickedCh {
+					t.profileCh <- name
+				}
+			}(name, item)
+		}
+		item.Show()
+		if name == t.cfg.ActiveProfile {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+	for name, item := range t.menu.profiles {
+		if _, ok := t.cfg.Profiles[name]; !ok {
+			item.Hide()
+		}
+	}
+}
+
+// switchProfile activates a profile chosen from the menu and saves the choice.
+func (t *TrayApp) switchProfile(name string) {
+	if name == t.cfg.ActiveProfile {
+		t.syncProfileItems() // undo the checkbox toggle of the click
+		return
+	}
+	cfg := *t.cfg
+	if err := cfg.SwitchProfile(name); err != nil {
+		slog.Error("Failed to switch profile", "err", err)
+		return
+	}
+	slog.Info("Switched profile", "profile", name)
+	t.applyConfig(&cfg)
+	t.saveConfig()
+}
+
+// openLog shows the log file in the default text viewer.
+func (t *TrayApp) openLog() {
+	path, err := logging.Path()
+	if err == nil {
+		err = openFile(path)
+	}
+	if err != nil {
+		slog.Error("Failed to open log", "err", err)
+	}
+}
+
+func (t *TrayApp) registerChord() {
+	timeout := time.Duration(t.cfg.ChordTimeoutMs) * time.Millisecond
+	if err := hotkey.RegisterChord(t.cfg.HotkeyModifiers, t.cfg.ChordLeaderKey, timeout); err != nil {
+		slog.Error("Failed to register chord leader", "err", err)
+	}
 }
 
 func (t *TrayApp) OnRunning() {
+	phase, cycle := t.timerPhase()
+	icon, label := runningIcon(phase), t.phaseLabel(phase, cycle)
+	t.viewMu.Lock()
+	note := heldNote(t.held)
+	t.viewMu.Unlock()
 	t.uiChan <- func() {
 		t.stopBlinking()
-		systray.SetIcon(IconRunning)
-		systray.SetTooltip("HydraReminder - Running")
+		systray.SetIcon(icon)
+		if label != "" {
+			systray.SetTooltip("HydraReminder - " + label + note)
+		} else {
+			systray.SetTooltip("HydraReminder - Running" + note)
+		}
 	}
 }
 
+// runningIcon is the icon for a Pomodoro phase, or IconRunning.
+func runningIcon(phase timer.Phase) []byte {
+	var icon []byte
+	switch phase {
+	case timer.PhaseWork:
+		icon = IconWork
+	case timer.PhaseShortBreak:
+		icon = IconShortBreak
+	case timer.PhaseLongBreak:
+		icon = IconLongBreak
+	}
+	if icon == nil {
+		return IconRunning
+	}
+	return icon
+}
+
+// phaseLabel names the Pomodoro phase, e.g. "Work 3/4", or returns "" outside
+// Pomodoro mode.
+func (t *TrayApp) phaseLabel(phase timer.Phase, cycle int) string {
+	switch phase {
+	case timer.PhaseWork:
+		return fmt.Sprintf("Work %d/%d", cycle, t.live.Load().Pomodoro.CyclesBeforeLongBreak)
+	case timer.PhaseShortBreak:
+		return "Short break"
+	case timer.PhaseLongBreak:
+		return "Long break"
+	}
+	return ""
+}
+
+// phaseLine is the menu's time line in Pomodoro mode, e.g. "Work 3/4 – 12:05 left".
+func phaseLine(label string, state timer.State, rem time.Duration) string {
+	left := fmt.Sprintf("%02d:%02d left", int(rem.Minutes()), int(rem.Seconds())%60)
+	switch state {
+	case timer.StateAlerting:
+		return label + " done (Alert!)"
+	case timer.StatePaused:
+		return label + " – " + left + " (Paused)"
+	}
+	return label + " – " + left
+}
+
+// heldNote reports the alerts alert_limit held back, e.g.
+// " (2 alerts merged into the last one)", or returns "".
+func heldNote(h timer.Held) string {
+	var parts []string
+	switch {
+	case h.Merged == 1:
+		parts = append(parts, "1 alert merged into the last one")
+	case h.Merged > 1:
+		parts = append(parts, fmt.Sprintf("%d alerts merged into the last one", h.Merged))
+	}
+	if h.Capped {
+		parts = append(parts, fmt.Sprintf("daily limit reached, %d held back", h.Today))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// alertText is the tooltip while alerting at the end of phase.
+func alertText(phase timer.Phase) string {
+	switch phase {
+	case timer.PhaseWork:
+		return "Work done, take a break!"
+	case timer.PhaseShortBreak, timer.PhaseLongBreak:
+		return "Break over, back to work!"
+	}
+	return "Stand Up / Drink Water!"
+}
+
 func (t *TrayApp) OnAlert() {
+	phase, _ := t.timerPhase()
 	t.uiChan <- func() {
-		if t.cfg.AlertStyle == "blink" {
-			t.startBlinking()
+		if t.live.Load().AlertStyle == "blink" {
+			t.startBlinking(phase)
 		} else {
 			// Just swap color
 			systray.SetIcon(IconAlert)
-			systray.SetTooltip("Stand Up / Drink Water!")
+			systray.SetTooltip(alertText(phase))
 		}
 	}
 }
@@ -363,13 +868,89 @@ func (t *TrayApp) OnStop() {
 	}
 }
 
-func (t *TrayApp) startBlinking() {
+func (t *TrayApp) OnPause() {
+	t.uiChan <- func() {
+		t.stopBlinking()
+		systray.SetIcon(IconStopped)
+		systray.SetTooltip("HydraReminder - Paused")
+	}
+}
+
+// phaseNotice is how long the alert icon shows after auto advance.
+const phaseNotice = 5 * time.Second
+
+// OnPhase shows for a moment that auto advance moved to the next Pomodoro
+// phase, there is no alert to acknowledge.
+func (t *TrayApp) OnPhase() {
+	label := t.phaseLabel(t.timerPhase())
+	t.uiChan <- func() {
+		t.stopBlinking()
+		systray.SetIcon(IconAlert)
+		systray.SetTooltip("HydraReminder - " + label + " started")
+	}
+	time.AfterFunc(phaseNotice, func() {
+		if t.timerManager.GetState() != timer.StateRunning {
+			return
+		}
+		phase, _ := t.timerPhase()
+		icon := runningIcon(phase)
+		t.uiChan <- func() {
+			systray.SetIcon(icon)
+		}
+	})
+}
+
+// OnBreak shows that an acknowledged alert started a break.
+func (t *TrayApp) OnBreak() {
+	t.uiChan <- func() {
+		t.stopBlinking()
+		icon := IconShortBreak
+		if icon == nil {
+			icon = IconStopped
+		}
+		systray.SetIcon(icon)
+		systray.SetTooltip("HydraReminder - On a break")
+	}
+}
+
+// syncAdaptive shows the adaptive interval's current suggestion.
+func (t *TrayApp) syncAdaptive() {
+	settings := t.live.Load().Adaptive
+	if !settings.Enabled {
+		t.adaptive.menu.Hide()
+		return
+	}
+	s := t.advisor.Suggestion()
+	t.adaptive.menu.Show()
+	t.adaptive.summary.SetTitle(s.Summary)
+	t.adaptive.reason.SetTitle(s.Reason)
+	if s.Change && !settings.Auto {
+		t.adaptive.apply.SetTitle(fmt.Sprintf("Apply %d min", s.Minutes))
+		t.adaptive.apply.Show()
+	} else {
+		t.adaptive.apply.Hide()
+	}
+}
+
+// syncBreakWarning shows the break tracker's warning, if any.
+func (t *TrayApp) syncBreakWarning() {
+	if w := t.breaks.Warning(); w != "" {
+		t.breakItem.SetTitle("⚠ " + w)
+		t.breakItem.Show()
+	} else {
+		t.breakItem.Hide()
+	}
+}
+
+// startBlinking alternates the alert icon with the one for phase.
+func (t *TrayApp) startBlinking(phase timer.Phase) {
 	t.stopBlinking() // Ensure any existing is stopped
 	t.blinkTicker = time.NewTicker(500 * time.Millisecond)
 	t.blinkDone = make(chan struct{})
 	t.iconIsAlert = true
 	systray.SetIcon(IconAlert)
-	systray.SetTooltip("Stand Up / Drink Water!")
+	systray.SetTooltip(alertText(phase))
+	running := runningIcon(phase)
 
 	go func() {
 		for {
@@ -379,7 +960,7 @@ func (t *TrayApp) startBlinking() {
 			case <-t.blinkTicker.C:
 				t.uiChan <- func() {
 					if t.iconIsAlert {
-						systray.SetIcon(IconRunning)
+						systray.SetIcon(running)
 					} else {
 						systray.SetIcon(IconAlert)
 					}
@@ -403,5 +984,9 @@ func (t *TrayApp) stopBlinking() {
 
 func (t *TrayApp) onExit() {
 	hotkey.Unregister()
+	hotkey.UnregisterChord()
+	for _, f := range t.atExit {
+		f()
+	}
 	os.Exit(0)
 }
@@ -3,43 +3,178 @@
 package tray
 
 import (
-	"bufio"
+	"log/slog"
+	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 
-	"hydra-reminder/internal/timer"
+	"github.com/godbus/dbus/v5"
 )
 
-// monitorMenuOpen on Linux uses dbus-monitor to detect when the user interacts
-// with the tray app indicator (e.g. clicks it to open the menu).
-func (t *TrayApp) monitorMenuOpen() {
-	// Monitor the StatusNotifierItem interface for interaction methods
-	// Use stdbuf -oL to prevent block buffering when piping stdout.
-	cmd := exec.Command("stdbuf", "-oL", "dbus-monitor", "--session", "interface='org.kde.StatusNotifierItem'")
-	stdout, err := cmd.StdoutPipe()
+const (
+	sniInterface      = "org.kde.StatusNotifierItem"
+	dbusmenuInterface = "com.canonical.dbusmenu"
+
+	watcherName = "org.kde.StatusNotifierWatcher"
+	watcherPath = dbus.ObjectPath("/StatusNotifierWatcher")
+
+	// defaultItemPath is where an item lives when the watcher only knows its bus name.
+	defaultItemPath = dbus.ObjectPath("/StatusNotifierItem")
+)
+
+// monitorTrayEvents watches the method calls the tray host makes on our
+// StatusNotifierItem and its menu. The item itself is exported by the systray
+// library on its own connection, so a second connection becomes a bus monitor
+// and keeps only the calls addressed to this process.
+func (t *TrayApp) monitorTrayEvents() {
+	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
+		slog.Warn("Tray events unavailable", "err", err)
 		return
 	}
-	if err := cmd.Start(); err != nil {
+	defer conn.Close()
+
+	mon, err := dbus.ConnectSessionBus()
+	if err != nil {
+		slog.Warn("Tray events unavailable", "err", err)
 		return
 	}
-	defer cmd.Process.Kill()
+	defer mon.Close()
 
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
+	rules := []string{
+		"type='method_call',interface='" + sniInterface + "'",
+		"type='method_call',interface='" + dbusmenuInterface + "'",
+	}
+	if err := mon.BusObject().Call("org.freedesktop.DBus.Monitoring.BecomeMonitor", 0, rules, uint32(0)).Err; err != nil {
+		slog.Warn("Tray events unavailable", "err", err)
+		return
+	}
+	msgs := make(chan *dbus.Message, 16)
+	mon.Eavesdrop(msgs)
 
-		// The host environment calls these methods when the user interacts with the tray icon.
-		if strings.Contains(line, "member=AboutToShow") ||
-			strings.Contains(line, "member=ContextMenu") ||
-			strings.Contains(line, "member=Activate") ||
-			strings.Contains(line, "member=SecondaryActivate") ||
-			strings.Contains(line, "member=SecondaryClick") ||
-			strings.Contains(line, "member=Scroll") {
+	owner := &itemOwner{conn: conn, pid: uint32(os.Getpid()), names: map[string]bool{}}
+	for msg := range msgs {
+		ev, ok := parseTrayEvent(msg)
+		if !ok || !owner.targets(msg) {
+			continue
+		}
+		t.onTrayEvent(ev)
+	}
+}
 
-			if t.timerManager != nil && t.timerManager.GetState() == timer.StateAlerting {
-				t.timerManager.Reset()
+// parseTrayEvent maps a StatusNotifierItem or dbusmenu method call to an event.
+func parseTrayEvent(msg *dbus.Message) (trayEvent, bool) {
+	if msg.Type != dbus.TypeMethodCall {
+		return trayEvent{}, false
+	}
+	iface, _ := msg.Headers[dbus.FieldInterface].Value().(string)
+	member, _ := msg.Headers[dbus.FieldMember].Value().(string)
+
+	switch iface {
+	case sniInterface:
+		switch member {
+		case "Activate":
+			return trayEvent{kind: eventActivate}, true
+		case "SecondaryActivate":
+			return trayEvent{kind: eventSecondaryActivate}, true
+		case "ContextMenu":
+			return trayEvent{kind: eventMenu}, true
+		case "Scroll":
+			// Scroll(delta int32, orientation string), only vertical scrolling is used.
+			if len(msg.Body) != 2 {
+				return trayEvent{}, false
+			}
+			delta, ok1 := msg.Body[0].(int32)
+			orientation, ok2 := msg.Body[1].(string)
+			if !ok1 || !ok2 || !strings.EqualFold(orientation, "vertical") || delta == 0 {
+				return trayEvent{}, false
 			}
+			return trayEvent{kind: eventScroll, delta: int(delta)}, true
 		}
+	case dbusmenuInterface:
+		// Hosts that show the menu themselves, on either button, announce it
+		// with an "opened" event on the root item.
+		if member == "Event" && len(msg.Body) >= 2 {
+			id, _ := msg.Body[0].(int32)
+			name, _ := msg.Body[1].(string)
+			if id == 0 && name == "opened" {
+				return trayEvent{kind: eventMenu}, true
+			}
+		}
+	}
+	return trayEvent{}, false
+}
+
+// itemOwner decides whether a call is addressed to this process's tray item.
+type itemOwner struct {
+	conn *dbus.Conn
+	pid  uint32
+	// names caches whether a bus name belongs to this process.
+	names map[string]bool
+	// path is the object path of our item, empty until the watcher reported it.
+	path dbus.ObjectPath
+}
+
+func (o *itemOwner) targets(msg *dbus.Message) bool {
+	dest, _ := msg.Headers[dbus.FieldDestination].Value().(string)
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	if dest == "" || !o.ownsName(dest) {
+		return false
+	}
+	if o.path == "" {
+		o.path = o.itemPath()
+	}
+	// Without a watcher to ask, a call to any object of this process will do.
+	// The menu is exported below the item, e.g. /org/ayatana/NotificationItem/x/Menu.
+	return o.path == "" || path == o.path || strings.HasPrefix(string(path), string(o.path)+"/")
+}
+
+func (o *itemOwner) ownsName(name string) bool {
+	if own, ok := o.names[name]; ok {
+		return own
+	}
+	var pid uint32
+	err := o.conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, name).Store(&pid)
+	if err != nil {
+		// The name may be gone already, don't cache a guess.
+		return false
+	}
+	own := pid == o.pid
+	o.names[name] = own
+	return own
+}
+
+// itemPath looks up our item in the watcher's registry. Entries are a bus name
+// optionally followed by the object path, "/StatusNotifierItem" if omitted.
+func (o *itemOwner) itemPath() dbus.ObjectPath {
+	v, err := o.conn.Object(watcherName, watcherPath).GetProperty(watcherName + ".RegisteredStatusNotifierItems")
+	if err != nil {
+		return ""
+	}
+	items, _ := v.Value().([]string)
+	for _, item := range items {
+		name, path := item, defaultItemPath
+		if i := strings.Index(item, "/"); i >= 0 {
+			name, path = item[:i], dbus.ObjectPath(item[i:])
+		}
+		if o.ownsName(name) {
+			return path
+		}
+	}
+	return ""
+}
+
+// openFile opens path in the user's default application for it.
+func openFile(path string) error {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	cmd := exec.Command(opener, path)
+	if err := cmd.Start(); err != nil {
+		return err
 	}
+	go cmd.Wait()
+	return nil
 }
@@ -3,15 +3,16 @@
 package tray
 
 import (
+	"os/exec"
 	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
-
-	"hydra-reminder/internal/timer"
 )
 
-func (t *TrayApp) monitorMenuOpen() {
+// monitorTrayEvents polls for our menu window, the Windows tray opens it on
+// either mouse button.
+func (t *TrayApp) monitorTrayEvents() {
 	user32 := windows.NewLazySystemDLL("user32.dll")
 	findWindow := user32.NewProc("FindWindowW")
 	getWindowThreadProcessId := user32.NewProc("GetWindowThreadProcessId")
@@ -36,10 +37,7 @@ func (t *TrayApp) monitorMenuOpen() {
 			if pid == myPid {
 				if !lastOpen {
 					lastOpen = true
-					// User opened menu, reset the timer ONLY if alerting
-					if t.timerManager != nil && t.timerManager.GetState() == timer.StateAlerting {
-						t.timerManager.Reset()
-					}
+					t.onTrayEvent(trayEvent{kind: eventMenu})
 				}
 			} else {
 				lastOpen = false
@@ -49,3 +47,13 @@ func (t *TrayApp) monitorMenuOpen() {
 		}
 	}
 }
+
+// openFile opens path in the user's default application for it.
+func openFile(path string) error {
+	cmd := exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+
+	"hydra-reminder/internal/config"
+)
+
+// received is a request as the stand-in server saw it.
+type received struct {
+	method, uri string
+	header      http.Header
+	body        []byte
+}
+
+// Test sends a sample event through every webhook that fires on it and writes
+// the requests to out. Unless live is set the requests go to a stand-in
+// server on 127.0.0.1 instead of the real endpoints, so templates and headers
+// can be checked without notifying anyone. Nothing is queued or retried.
+func Test(hooks []config.Webhook, event, profile string, live bool, out io.Writer) error {
+	p := Payload{
+		Event:            event,
+		At:               time.Now().UTC().Truncate(time.Second),
+		Profile:          profile,
+		RemainingSeconds: 0,
+		Acknowledged:     event != "alert",
+	}
+	if event != "alert" {
+		p.RemainingSeconds = 30 * 60
+	}
+
+	var standIn *url.URL
+	got := make(chan received, 1)
+	if !live {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got <- received{r.Method, r.URL.RequestURI(), r.Header, body}
+		})}
+		go srv.Serve(ln)
+		defer srv.Close()
+		standIn = &url.URL{Scheme: "http", Host: ln.Addr().String()}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	sent := 0
+	for i, w := range hooks {
+		if len(w.Events) > 0 && !slices.Contains(w.Events, event) {
+			continue
+		}
+		sent++
+		fmt.Fprintf(out, "Webhook %d: %s\n", i+1, w.URL)
+		req, err := render(w, p)
+		if err != nil {
+			fmt.Fprintf(out, "  error: %v\n\n", err)
+			continue
+		}
+		if !live {
+			target, err := url.Parse(req.URL)
+			if err != nil {
+				fmt.Fprintf(out, "  error: %v\n\n", err)
+				continue
+			}
+			target.Scheme, target.Host, target.User = standIn.Scheme, standIn.Host, nil
+			req.URL = target.String()
+		}
+
+		err = send(client, req)
+		if live {
+			if err != nil {
+				fmt.Fprintf(out, "  failed: %v\n\n", err)
+			} else {
+				fmt.Fprintf(out, "  delivered\n\n")
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(out, "  error: %v\n\n", err)
+			continue
+		}
+		printReceived(out, <-got)
+	}
+	if sent == 0 {
+		fmt.Fprintf(out, "No webhook fires on %q.\n", event)
+	}
+	return nil
+}
+
+func printReceived(out io.Writer, r received) {
+	fmt.Fprintf(out, "  %s %s\n", r.method, r.uri)
+	names := make([]string, 0, len(r.header))
+	for name := range r.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range r.header[name] {
+			fmt.Fprintf(out, "  %s: %s\n", name, v)
+		}
+	}
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, r.body, "  ", "  ") == nil {
+		fmt.Fprintf(out, "\n  %s\n\n", pretty.String())
+	} else {
+		fmt.Fprintf(out, "\n  %s\n\n", r.body)
+	}
+}
@@ -0,0 +1,346 @@
+// Package webhook sends the configured webhooks on timer events. Deliveries
+// are queued in a file in the config directory and retried with exponential
+// backoff, so events survive network outages and restarts.
+//
+// A webhook's template is a Go text/template that renders the request body
+// from a Payload, e.g.
+//
+//	{"text": {{printf "%s on profile %s" .Event .Profile | json}}}
+//
+// where json encodes a value as JSON. Without a template the body is the
+// Payload itself as JSON.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/timer"
+)
+
+// QueueFile is the queue's file name in config.Dir.
+const QueueFile = "webhook-queue.json"
+
+const (
+	// firstRetry is the delay before the first retry, it doubles with every attempt.
+	firstRetry = 5 * time.Second
+	maxRetry   = time.Hour
+	// maxAge is when an undelivered event is given up.
+	maxAge = 24 * time.Hour
+	// maxQueue bounds the queue while an endpoint is down for long.
+	maxQueue = 1000
+)
+
+// Payload is what a webhook reports, and what its template renders from.
+type Payload struct {
+	Event            string    `json:"event"` // "alert", "reset" or "snooze"
+	At               time.Time `json:"at"`
+	Profile          string    `json:"profile"`
+	RemainingSeconds int       `json:"remaining_seconds"` // until the next alert, 0 on alert
+	// Acknowledged is true for a reset or snooze that ended an alert.
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// delivery is one queued request.
+type delivery struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"`
+	Body        string            `json:"body"`
+	Created     time.Time         `json:"created"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"next_attempt"`
+}
+
+// Dispatcher queues and delivers webhook requests.
+type Dispatcher struct {
+	cfg    *config.Live
+	path   string
+	client *http.Client
+
+	mu    sync.Mutex
+	queue []*delivery
+	dirty bool
+	wake  chan struct{}
+}
+
+// New returns a dispatcher for the webhooks currently in cfg, with the
+// deliveries left in the queue file by the previous run. Start it with Run.
+func New(cfg *config.Live) (*Dispatcher, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	d := &Dispatcher{
+		cfg:    cfg,
+		path:   filepath.Join(dir, QueueFile),
+		client: &http.Client{Timeout: 10 * time.Second},
+		wake:   make(chan struct{}, 1),
+	}
+	data, err := os.ReadFile(d.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &d.queue); err != nil {
+			slog.Warn("Dropping unreadable webhook queue", "err", err)
+			d.queue = nil
+			d.dirty = true
+		} else if len(d.queue) > 0 {
+			slog.Info("Resuming queued webhook deliveries", "count", len(d.queue))
+		}
+	}
+	return d, nil
+}
+
+// Observe queues the webhooks for alert, reset and snooze events. It is meant
+// for timer.Manager.Subscribe and does not block.
+func (d *Dispatcher) Observe(ev timer.Event) {
+	var name string
+	switch ev.Kind {
+	case timer.EventAlert, timer.EventPhase:
+		// Auto advance moves to the next Pomodoro phase instead of alerting.
+		name = "alert"
+	case timer.EventReset, timer.EventBreak:
+		// With break tracking on, acknowledging starts a break instead of a reset.
+		name = "reset"
+	case timer.EventSnooze:
+		name = "snooze"
+	default:
+		return
+	}
+	cfg := d.cfg.Load()
+	p := Payload{
+		Event:            name,
+		At:               ev.At.UTC().Truncate(time.Second),
+		Profile:          cfg.ActiveProfile,
+		RemainingSeconds: int(ev.Countdown.Round(time.Second).Seconds()),
+		Acknowledged:     ev.From == timer.StateAlerting,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, w := range cfg.Webhooks {
+		if len(w.Events) > 0 && !slices.Contains(w.Events, name) {
+			continue
+		}
+		req, err := render(w, p)
+		if err != nil {
+			slog.Error("Webhook not sent", "url", w.URL, "err", err)
+			continue
+		}
+		if len(d.queue) >= maxQueue {
+			slog.Warn("Webhook queue full, dropping the oldest delivery", "url", d.queue[0].URL)
+			d.queue = d.queue[1:]
+		}
+		d.queue = append(d.queue, req)
+		d.dirty = true
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	// Past 11 attempts the delay is maxRetry anyway, and shifting further
+	// would overflow.
+	return min(firstRetry<<(min(attempts, 11)-1), maxRetry)
+}
+
+// render builds the request w sends for p.
+func render(w config.Webhook, p Payload) (*delivery, error) {
+	body, err := renderBody(w.Template, p)
+	if err != nil {
+		return nil, err
+	}
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &delivery{
+		URL:         w.URL,
+		Method:      method,
+		Headers:     w.Headers,
+		Body:        body,
+		Created:     p.At,
+		NextAttempt: p.At,
+	}, nil
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func renderBody(tmpl string, p Payload) (string, error) {
+	if tmpl == "" {
+		b, err := json.Marshal(p)
+		return string(b), err
+	}
+	t, err := template.New("webhook").Funcs(funcs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, p); err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	if !json.Valid([]byte(b.String())) {
+		return "", fmt.Errorf("template did not render valid JSON: %s", b.String())
+	}
+	return b.String(), nil
+}
+
+// Run delivers queued requests until stop is closed.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	for {
+		next := d.deliverDue()
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timeout = time.After(time.Until(next))
+		}
+		select {
+		case <-d.wake:
+		case <-timeout:
+		case <-stop:
+			d.save()
+			return
+		}
+	}
+}
+
+// deliverDue sends every delivery that is due and returns when the next one
+// is, zero if the queue is empty.
+func (d *Dispatcher) deliverDue() time.Time {
+	d.save()
+
+	d.mu.Lock()
+	var due []*delivery
+	now := time.Now()
+	for _, req := range d.queue {
+		if !req.NextAttempt.After(now) {
+			due = append(due, req)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, req := range due {
+		err := send(d.client, req)
+		d.mu.Lock()
+		switch {
+		case err == nil:
+			d.remove(req)
+		case errors.Is(err, errPermanent):
+			slog.Warn("Webhook rejected the event, dropping it", "url", req.URL, "err", err)
+			d.remove(req)
+		case time.Since(req.Created) > maxAge:
+			slog.Warn("Webhook kept failing, dropping the event", "url", req.URL, "age", maxAge, "err", err)
+			d.remove(req)
+		default:
+			req.Attempts++
+			delay := retryDelay(req.Attempts)
+			req.NextAttempt = time.Now().Add(delay)
+			slog.Warn("Webhook failed, retrying", "url", req.URL, "in", delay, "err", err)
+		}
+		d.dirty = true
+		d.mu.Unlock()
+	}
+	d.save()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var next time.Time
+	for _, req := range d.queue {
+		if next.IsZero() || req.NextAttempt.Before(next) {
+			next = req.NextAttempt
+		}
+	}
+	return next
+}
+
+// Internal func, assumes lock is held
+func (d *Dispatcher) remove(req *delivery) {
+	if i := slices.Index(d.queue, req); i >= 0 {
+		d.queue = slices.Delete(d.queue, i, i+1)
+	}
+}
+
+// save writes the queue if it changed since the last save.
+func (d *Dispatcher) save() {
+	d.mu.Lock()
+	if !d.dirty {
+		d.mu.Unlock()
+		return
+	}
+	data, err := json.MarshalIndent(d.queue, "", "  ")
+	d.dirty = false
+	d.mu.Unlock()
+	if err != nil {
+		slog.Error("Failed to save webhook queue", "err", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		slog.Error("Failed to save webhook queue", "err", err)
+		return
+	}
+	// The queue holds webhook headers, which may be credentials.
+	if err := config.WriteFileAtomic(d.path, data, 0600); err != nil {
+		slog.Error("Failed to save webhook queue", "err", err)
+	}
+}
+
+// errPermanent marks responses that retrying will not fix.
+var errPermanent = errors.New("permanent failure")
+
+// send makes one attempt at req. Client errors other than 408 and 429 are
+// wrapped in errPermanent.
+func send(client *http.Client, req *delivery) error {
+	var body io.Reader
+	if req.Method != http.MethodGet {
+		body = bytes.NewBufferString(req.Body)
+	}
+	r, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("User-Agent", "hydra-reminder")
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", errPermanent, resp.Status)
+	}
+	return errors.New(resp.Status)
+}
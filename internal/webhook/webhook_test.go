@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hydra-reminder/internal/config"
+	"hydra-reminder/internal/timer"
+)
+
+func newTestDispatcher(t *testing.T, hooks ...config.Webhook) *Dispatcher {
+	t.Helper()
+	config.SetDir(t.TempDir())
+	t.Cleanup(func() { config.SetDir("") })
+
+	cfg := config.DefaultConfig()
+	cfg.Webhooks = hooks
+	d, err := New(config.NewLive(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// statusServer answers with the given status codes in turn, then 200.
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(codes) {
+			w.WriteHeader(codes[n-1])
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+func alert() timer.Event {
+	return timer.Event{Kind: timer.EventAlert, From: timer.StateRunning, At: time.Now()}
+}
+
+func TestRenderBody(t *testing.T) {
+	p := Payload{Event: "reset", Profile: "work", RemainingSeconds: 1800, Acknowledged: true}
+
+	got, err := renderBody(`{"text": {{printf "%s on %s" .Event .Profile | json}}, "left": {{.RemainingSeconds}}}`, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text": "reset on work", "left": 1800}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got, err = renderBody("", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, `"event":"reset"`) || !strings.Contains(got, `"acknowledged":true`) {
+		t.Errorf("default body %s lacks the payload", got)
+	}
+
+	if _, err := renderBody(`{"text": {{.Event}}}`, p); err == nil {
+		t.Error("expected an error for a template rendering invalid JSON")
+	}
+	if _, err := renderBody(`{{.Missing}}`, p); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestObserveFiltersEvents(t *testing.T) {
+	d := newTestDispatcher(t,
+		config.Webhook{URL: "http://127.0.0.1:1/all"},
+		config.Webhook{URL: "http://127.0.0.1:1/reset", Events: []string{"reset"}},
+	)
+	d.Observe(alert())
+	d.Observe(timer.Event{Kind: timer.EventPause, At: time.Now()})
+	if len(d.queue) != 1 || d.queue[0].URL != "http://127.0.0.1:1/all" {
+		t.Fatalf("queue = %+v, want one delivery to /all", d.queue)
+	}
+	if d.queue[0].Method != http.MethodPost {
+		t.Errorf("method = %s, want POST by default", d.queue[0].Method)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	ts, calls := statusServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	d := newTestDispatcher(t, config.Webhook{URL: ts.URL})
+	d.Observe(alert())
+
+	for attempt, want := range []time.Duration{firstRetry, 2 * firstRetry} {
+		next := d.deliverDue()
+		if len(d.queue) != 1 {
+			t.Fatalf("attempt %d: delivery dropped", attempt+1)
+		}
+		if delay := time.Until(next); delay < want-time.Second || delay > want {
+			t.Errorf("attempt %d: retry in %v, want %v", attempt+1, delay, want)
+		}
+		d.queue[0].NextAttempt = time.Now()
+	}
+
+	if next := d.deliverDue(); !next.IsZero() || len(d.queue) != 0 {
+		t.Errorf("queue = %+v after a successful delivery, want empty", d.queue)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+}
+
+func TestRetryDelayCapped(t *testing.T) {
+	for _, attempts := range []int{11, 12, 32, 64, 1000} {
+		if got := retryDelay(attempts); got != maxRetry {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, maxRetry)
+		}
+	}
+	if got := retryDelay(10); got >= maxRetry || got <= 0 {
+		t.Errorf("retryDelay(10) = %v, want below %v", got, maxRetry)
+	}
+}
+
+func TestPermanentFailureDropped(t *testing.T) {
+	ts, _ := statusServer(t, http.StatusNotFound)
+	d := newTestDispatcher(t, config.Webhook{URL: ts.URL})
+	d.Observe(alert())
+	d.deliverDue()
+	if len(d.queue) != 0 {
+		t.Errorf("delivery rejected with 404 is still queued")
+	}
+}
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	ts, calls := statusServer(t, http.StatusBadGateway)
+	hook := config.Webhook{URL: ts.URL, Headers: map[string]string{"X-Token": "abc"}}
+	d := newTestDispatcher(t, hook)
+	d.Observe(alert())
+	d.deliverDue()
+
+	cfg := config.DefaultConfig()
+	cfg.Webhooks = []config.Webhook{hook}
+	restarted, err := New(config.NewLive(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.queue) != 1 || restarted.queue[0].Attempts != 1 ||
+		restarted.queue[0].Headers["X-Token"] != "abc" {
+		t.Fatalf("queue after restart = %+v", restarted.queue)
+	}
+	restarted.queue[0].NextAttempt = time.Now()
+	restarted.deliverDue()
+	if len(restarted.queue) != 0 || calls.Load() != 2 {
+		t.Errorf("queued delivery was not sent after the restart")
+	}
+}
+
+func TestTestUsesStandIn(t *testing.T) {
+	hooks := []config.Webhook{
+		{
+			URL:      "https://hooks.example.invalid/services/abc?channel=water",
+			Headers:  map[string]string{"X-Token": "abc"},
+			Template: `{"text": {{.Event | json}}}`,
+		},
+		{URL: "https://example.invalid/only-reset", Events: []string{"reset"}},
+	}
+	var out strings.Builder
+	if err := Test(hooks, "alert", "work", false, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"POST /services/abc?channel=water", "X-Token: abc", `"text": "alert"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "only-reset") {
+		t.Errorf("webhook for reset ran on alert:\n%s", out.String())
+	}
+}